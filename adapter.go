@@ -0,0 +1,350 @@
+package gobioinfo
+
+import (
+	"fmt"
+)
+
+/*
+Adapter trimming.
+
+An AdapterTrimmer removes adapters from reads as cutadapt does. Each adapter is aligned
+to the read semi-globally, with the ends of the adapter and the read which may be left
+out set by the type of the adapter: a 3' adapter is found with the alignment of
+SG3pAlign (the start of the adapter anchored within the read), a 5' adapter with the
+mirror image of it, and an anchored adapter must lie whole at its end of the read. The
+alignments are scored as cutadapt scores them (match 1, mismatch -1, gap -2), so that
+the best match is the one with the most matches for its errors. An ambiguity code in the
+read scores as a mismatch unless MatchReadWildcards is set, as it counts as an error.
+
+A match is accepted if it covers enough of the adapter and has few enough errors
+(mismatches and gap positions) for its length. The adapter is then cut out of the read,
+along with everything after it (a 3' adapter) or before it (a 5' adapter), cutting the
+quality scores with the sequence.
+*/
+
+// AdapterType is where an adapter lies in a read, and which part of the read is removed
+// with it
+type AdapterType int
+
+// Adapter types, with the cutadapt option for each
+const (
+	// ThreePrimeAdapter (-a ADAPTER) may be anywhere in the read, or partly off its 3'
+	// end, and is removed with everything after it
+	ThreePrimeAdapter AdapterType = iota
+	// FivePrimeAdapter (-g ADAPTER) may be anywhere in the read, or partly off its 5'
+	// end, and is removed with everything before it
+	FivePrimeAdapter
+	// AnchoredThreePrimeAdapter (-a ADAPTER$) must be whole at the 3' end of the read
+	AnchoredThreePrimeAdapter
+	// AnchoredFivePrimeAdapter (-g ^ADAPTER) must be whole at the 5' end of the read
+	AnchoredFivePrimeAdapter
+	// LinkedAdapter (-a ADAPTER1...ADAPTER2) is a 5' adapter followed by a 3' adapter,
+	// given as the Front and Back of the Adapter
+	LinkedAdapter
+)
+
+// String returns the name of the adapter type
+func (t AdapterType) String() string {
+	switch t {
+	case ThreePrimeAdapter:
+		return "3'"
+	case FivePrimeAdapter:
+		return "5'"
+	case AnchoredThreePrimeAdapter:
+		return "anchored 3'"
+	case AnchoredFivePrimeAdapter:
+		return "anchored 5'"
+	case LinkedAdapter:
+		return "linked"
+	}
+	return fmt.Sprintf("AdapterType(%d)", int(t))
+}
+
+// Adapter is a sequence to trim from reads
+type Adapter struct {
+	Name     string
+	Sequence NucleotideSequence
+	Type     AdapterType
+	// Front and Back are the parts of a LinkedAdapter, in place of the Sequence: a 5'
+	// adapter (anchored or not) and a 3' adapter, which is searched for in what is left of
+	// the read after the 5' adapter is removed. As in cutadapt, an anchored part must be
+	// found for the read to be trimmed at all, while a part which is not anchored is
+	// optional.
+	Front *Adapter
+	Back  *Adapter
+}
+
+// AdapterTrimOptions holds the settings of an AdapterTrimmer. Start from
+// DefaultAdapterTrimOptions, as the zero value allows no errors.
+type AdapterTrimOptions struct {
+	// ErrorRate is the number of errors allowed for each base of the adapter matched,
+	// rounded down: 0.1 allows one error in a 10 to 19 base match
+	ErrorRate float64
+	// MinOverlap is the fewest bases of an adapter which must be matched when it is partly
+	// off the end of the read, or the whole adapter if it is shorter
+	MinOverlap int
+	// Times is the number of rounds of trimming (at least 1); each round removes the best
+	// matching adapter
+	Times int
+	// MatchReadWildcards lets an N or other ambiguity code in the read match the adapter;
+	// otherwise only ambiguity codes in the adapter are wildcards
+	MatchReadWildcards bool
+}
+
+// DefaultAdapterTrimOptions are the defaults of cutadapt
+var DefaultAdapterTrimOptions = AdapterTrimOptions{ErrorRate: 0.1, MinOverlap: 3, Times: 1}
+
+// readWildcardScoring scores adapter alignments as cutadapt does, with ambiguity codes
+// in the read matching the adapter
+var readWildcardScoring = Scoring{Match: 1, Mismatch: -1, GapOpen: 2, GapExtend: 2, N: 1}
+
+// adapterWildcardScoring scores adapter alignments in the same way, but only ambiguity
+// codes in the adapter (the query) are wildcards, and one in the read is a mismatch
+// against an unambiguous adapter base
+var adapterWildcardScoring = func() Scoring {
+	sc := readWildcardScoring
+	m := NewIUPACMatrix(sc)
+	for read := range m.scores {
+		for adapter := range m.scores[read] {
+			readBases, adapterBases := iupacBases(rune(read)), iupacBases(rune(adapter))
+			if readBases&(readBases-1) != 0 && adapterBases&(adapterBases-1) == 0 {
+				m.scores[read][adapter] = sc.Mismatch
+			}
+		}
+	}
+	sc.Matrix = m
+	return sc
+}()
+
+// adapterScoring returns the scoring of adapter alignments, so that a match is chosen by
+// the same rules as find counts its errors
+func adapterScoring(opts AdapterTrimOptions) Scoring {
+	if opts.MatchReadWildcards {
+		return readWildcardScoring
+	}
+	return adapterWildcardScoring
+}
+
+// AdapterMatch records an adapter found in a read and trimmed from it
+type AdapterMatch struct {
+	Adapter string      // Name of the adapter
+	Type    AdapterType // the type of the adapter, or of the part of a LinkedAdapter
+	// ReadStart and ReadEnd are the bases of the read matched, counting along the read as
+	// it was before this match was trimmed
+	ReadStart int
+	ReadEnd   int
+	// AdapterStart and AdapterEnd are the bases of the adapter matched
+	AdapterStart int
+	AdapterEnd   int
+	Matches      int
+	Errors       int
+	// Removed is the part of the read trimmed off: the adapter and the bases beyond it
+	Removed NucleotideSequence
+	// Alignment is the alignment of the adapter (the query) to the read (the subject)
+	Alignment PairWiseAlignment
+}
+
+// adapterPart is an adapter, or a part of a LinkedAdapter, ready to be searched for
+type adapterPart struct {
+	name     string
+	kind     AdapterType
+	sequence NucleotideSequence
+	aligner  *Aligner
+	required bool // whether the read is left untrimmed if this part is not found
+}
+
+// AdapterTrimmer trims adapters from reads. Like an Aligner, it must not be used by more
+// than one goroutine at a time.
+type AdapterTrimmer struct {
+	adapters [][]adapterPart // each adapter, as one part or the parts of a LinkedAdapter
+	opts     AdapterTrimOptions
+}
+
+// NewAdapterTrimmer returns an AdapterTrimmer for the adapters, or an error if one has no
+// sequence or an unknown type
+func NewAdapterTrimmer(adapters []Adapter, opts AdapterTrimOptions) (*AdapterTrimmer, error) {
+
+	if len(adapters) == 0 {
+		return nil, fmt.Errorf("no adapters to trim")
+	}
+	if opts.Times < 1 {
+		opts.Times = 1
+	}
+
+	t := &AdapterTrimmer{opts: opts}
+	for _, adapter := range adapters {
+		if adapter.Type != LinkedAdapter {
+			part, err := newAdapterPart(adapter.Name, adapter, adapterScoring(opts))
+			if err != nil {
+				return nil, err
+			}
+			t.adapters = append(t.adapters, []adapterPart{part})
+			continue
+		}
+
+		if adapter.Front == nil || adapter.Back == nil {
+			return nil, fmt.Errorf("linked adapter %q needs both a Front and a Back", adapter.Name)
+		}
+		if adapter.Front.Type != FivePrimeAdapter && adapter.Front.Type != AnchoredFivePrimeAdapter {
+			return nil, fmt.Errorf("linked adapter %q: the Front must be a 5' adapter, not %v", adapter.Name, adapter.Front.Type)
+		}
+		if adapter.Back.Type != ThreePrimeAdapter && adapter.Back.Type != AnchoredThreePrimeAdapter {
+			return nil, fmt.Errorf("linked adapter %q: the Back must be a 3' adapter, not %v", adapter.Name, adapter.Back.Type)
+		}
+		front, err := newAdapterPart(adapter.Name, *adapter.Front, adapterScoring(opts))
+		if err != nil {
+			return nil, err
+		}
+		back, err := newAdapterPart(adapter.Name, *adapter.Back, adapterScoring(opts))
+		if err != nil {
+			return nil, err
+		}
+		front.required = front.kind == AnchoredFivePrimeAdapter
+		back.required = back.kind == AnchoredThreePrimeAdapter
+		t.adapters = append(t.adapters, []adapterPart{front, back})
+	}
+
+	return t, nil
+}
+
+// newAdapterPart sets up the search for an adapter which is not linked, scored by sc
+func newAdapterPart(name string, adapter Adapter, sc Scoring) (adapterPart, error) {
+
+	if len(adapter.Sequence) == 0 {
+		return adapterPart{}, fmt.Errorf("adapter %q has no sequence", name)
+	}
+
+	// the adapter is the query and the read the subject
+	var ends FreeEnds
+	switch adapter.Type {
+	case ThreePrimeAdapter:
+		ends = FreeEnds{QueryEnd: true, SubjectStart: true, SubjectEnd: true}
+	case FivePrimeAdapter:
+		ends = FreeEnds{QueryStart: true, SubjectStart: true, SubjectEnd: true}
+	case AnchoredThreePrimeAdapter:
+		ends = FreeEnds{SubjectStart: true}
+	case AnchoredFivePrimeAdapter:
+		ends = FreeEnds{SubjectEnd: true}
+	default:
+		return adapterPart{}, fmt.Errorf("adapter %q has an unknown type %v", name, adapter.Type)
+	}
+
+	opts := AlignOptions{Mode: SemiGlobal, Ends: ends, Scoring: sc, Ties: TieBreak{Ends: LeftmostEnd}}
+	return adapterPart{
+		name:     name,
+		kind:     adapter.Type,
+		sequence: adapter.Sequence,
+		aligner:  NewAligner(adapter.Sequence, opts),
+	}, nil
+}
+
+// Trim removes adapters from the read, in up to opts.Times rounds, and returns the
+// trimmed read and the matches trimmed, in the order they were found. In each round the
+// adapter with the best scoring match is removed, the first given if two score the same;
+// the rounds stop early once no adapter is found. The trimmed read shares its sequence
+// and qualities with the read given.
+func (t *AdapterTrimmer) Trim(r FASTQRead) (FASTQRead, []AdapterMatch) {
+
+	var matches []AdapterMatch
+	for round := 0; round < t.opts.Times; round++ {
+
+		found := false
+		var best FASTQRead
+		var bestMatches []AdapterMatch
+		bestScore := 0
+		for _, parts := range t.adapters {
+			trimmed, partMatches, score, ok := t.trimAdapter(parts, r)
+			if ok && (!found || score > bestScore) {
+				found, best, bestMatches, bestScore = true, trimmed, partMatches, score
+			}
+		}
+		if !found {
+			break
+		}
+
+		r = best
+		matches = append(matches, bestMatches...)
+	}
+
+	return r, matches
+}
+
+// trimAdapter trims the parts of one adapter from the read in turn, returning the
+// trimmed read, its matches and their total score, or false if no part was found or a
+// required part was not
+func (t *AdapterTrimmer) trimAdapter(parts []adapterPart, r FASTQRead) (FASTQRead, []AdapterMatch, int, bool) {
+
+	var matches []AdapterMatch
+	score := 0
+	for k := range parts {
+		m, ok := t.find(&parts[k], r.Sequence)
+		if !ok {
+			if parts[k].required {
+				return r, nil, 0, false
+			}
+			continue
+		}
+
+		switch m.Type {
+		case ThreePrimeAdapter, AnchoredThreePrimeAdapter:
+			m.Removed = r.Sequence[m.ReadStart:]
+			r = r.sub(0, m.ReadStart)
+		default:
+			m.Removed = r.Sequence[:m.ReadEnd]
+			r = r.sub(m.ReadEnd, len(r.Sequence))
+		}
+		matches = append(matches, m)
+		score += m.Alignment.Score
+	}
+
+	return r, matches, score, len(matches) > 0
+}
+
+// find aligns a part to the read, and returns its match if it is long enough and has few
+// enough errors
+func (t *AdapterTrimmer) find(part *adapterPart, read NucleotideSequence) (AdapterMatch, bool) {
+
+	alignment := part.aligner.Align(read)
+	if alignment.ExpandedCIGAR == "" {
+		return AdapterMatch{}, false
+	}
+
+	m := AdapterMatch{
+		Adapter:      part.name,
+		Type:         part.kind,
+		ReadStart:    alignment.SubjectStart,
+		ReadEnd:      alignment.SubjectStart + alignment.SubjectAlignLen,
+		AdapterStart: alignment.QueryStart,
+		AdapterEnd:   alignment.QueryStart + alignment.QueryAlignLen,
+		Alignment:    alignment,
+	}
+
+	gappedQuery := []rune(alignment.GappedQuery)
+	for k, column := range []rune(alignment.ExpandedCIGAR) {
+		switch column {
+		case 'm':
+			m.Matches++
+		case 'n':
+			// an ambiguity code in the adapter is a wildcard, but one in the read only
+			// matches if MatchReadWildcards is set
+			if bases := iupacBases(gappedQuery[k]); t.opts.MatchReadWildcards || bases&(bases-1) != 0 {
+				m.Matches++
+			} else {
+				m.Errors++
+			}
+		default:
+			m.Errors++
+		}
+	}
+
+	overlap := m.AdapterEnd - m.AdapterStart
+	minOverlap := t.opts.MinOverlap
+	if minOverlap > len(part.sequence) {
+		minOverlap = len(part.sequence)
+	}
+	if overlap < minOverlap || m.Errors > int(t.opts.ErrorRate*float64(overlap)) {
+		return AdapterMatch{}, false
+	}
+
+	return m, true
+}
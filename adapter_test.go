@@ -0,0 +1,119 @@
+package gobioinfo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// func (t *AdapterTrimmer) Trim(r FASTQRead) (FASTQRead, []AdapterMatch)
+func TestAdapterTrimmer(t *testing.T) {
+	fmt.Println("testing AdapterTrimmer.Trim()...")
+
+	illumina := NucleotideSequence("AGATCGGAAGAGC")
+	smallRNA := NucleotideSequence("TGGAATTCTCGG")
+	front := NucleotideSequence("ACACGACGCTCTTCCGATCT")
+
+	linked := Adapter{Name: "linked", Type: LinkedAdapter,
+		Front: &Adapter{Sequence: NucleotideSequence("ACGTAC"), Type: AnchoredFivePrimeAdapter},
+		Back:  &Adapter{Sequence: NucleotideSequence("TTTTCC"), Type: ThreePrimeAdapter}}
+
+	type testPair struct {
+		adapters []Adapter
+		opts     AdapterTrimOptions
+		read     string
+		trimmed  string
+		matches  int
+	}
+
+	testSuite := []testPair{
+		// a 3' adapter inside the read, partly off its end, and too short a part to count
+		{[]Adapter{{Sequence: illumina}}, DefaultAdapterTrimOptions, "CCCCCCCCAGATCGGAAGAGCTTTT", "CCCCCCCC", 1},
+		{[]Adapter{{Sequence: illumina}}, DefaultAdapterTrimOptions, "CCCCCCCCAGATC", "CCCCCCCC", 1},
+		{[]Adapter{{Sequence: illumina}}, DefaultAdapterTrimOptions, "CCCCCCCCAG", "CCCCCCCCAG", 0},
+		// at the very start of the read it removes everything
+		{[]Adapter{{Sequence: illumina}}, DefaultAdapterTrimOptions, "AGATCGGAAGAGCTT", "", 1},
+		// one mismatch in 13 bases is allowed at 10%, but not two
+		{[]Adapter{{Sequence: illumina}}, DefaultAdapterTrimOptions, "CCCCCCCCAGATCGCAAGAGCTT", "CCCCCCCC", 1},
+		{[]Adapter{{Sequence: illumina}}, DefaultAdapterTrimOptions, "CCCCCCCCAGTTCGCAAGAGCTT", "CCCCCCCCAGTTCGCAAGAGCTT", 0},
+		// and an N in the read only matches with MatchReadWildcards
+		{[]Adapter{{Sequence: illumina}}, AdapterTrimOptions{MinOverlap: 3}, "CCCCCCCCAGATNGGAAGAGCTT", "CCCCCCCCAGATNGGAAGAGCTT", 0},
+		{[]Adapter{{Sequence: illumina}}, AdapterTrimOptions{MinOverlap: 3, MatchReadWildcards: true}, "CCCCCCCCAGATNGGAAGAGCTT", "CCCCCCCC", 1},
+		{[]Adapter{{Sequence: NucleotideSequence("AGATNGGAAGAGC")}}, AdapterTrimOptions{MinOverlap: 3}, "CCCCCCCCAGATCGGAAGAGCTT", "CCCCCCCC", 1},
+		// so a run of Ns in the read does not hide the adapter next to it
+		{[]Adapter{{Sequence: illumina}}, DefaultAdapterTrimOptions, "GGGGNNNNNNNNNNNNNNNNAGATCGGAAGAGCTT", "GGGGNNNNNNNNNNNNNNNN", 1},
+		{[]Adapter{{Sequence: illumina}}, DefaultAdapterTrimOptions, "GGGGGGGGNNNNNNNNNNNNNAGATC", "GGGGGGGGNNNNNNNNNNNNN", 1},
+		// 5' adapters, inside the read and partly off its start
+		{[]Adapter{{Sequence: front, Type: FivePrimeAdapter}}, DefaultAdapterTrimOptions, "TTACACGACGCTCTTCCGATCTGGGGGG", "GGGGGG", 1},
+		{[]Adapter{{Sequence: front, Type: FivePrimeAdapter}}, DefaultAdapterTrimOptions, "CCGATCTGGGGGG", "GGGGGG", 1},
+		// anchored adapters must be whole at their end of the read
+		{[]Adapter{{Sequence: NucleotideSequence("ACGTTT"), Type: AnchoredFivePrimeAdapter}}, DefaultAdapterTrimOptions, "ACGTTTGGGG", "GGGG", 1},
+		{[]Adapter{{Sequence: NucleotideSequence("ACGTTT"), Type: AnchoredFivePrimeAdapter}}, DefaultAdapterTrimOptions, "CACGTTTGGGG", "CACGTTTGGGG", 0},
+		{[]Adapter{{Sequence: NucleotideSequence("TTTACG"), Type: AnchoredThreePrimeAdapter}}, DefaultAdapterTrimOptions, "GGGGTTTACG", "GGGG", 1},
+		{[]Adapter{{Sequence: NucleotideSequence("TTTACG"), Type: AnchoredThreePrimeAdapter}}, DefaultAdapterTrimOptions, "GGGGTTTACGA", "GGGGTTTACGA", 0},
+		// a linked adapter: the anchored 5' part is required and the 3' part optional
+		{[]Adapter{linked}, DefaultAdapterTrimOptions, "ACGTACGGGGGTTTTCCAA", "GGGGG", 2},
+		{[]Adapter{linked}, DefaultAdapterTrimOptions, "ACGTACGGGGG", "GGGGG", 1},
+		{[]Adapter{linked}, DefaultAdapterTrimOptions, "CCCCCCGGGGGTTTTCCAA", "CCCCCCGGGGGTTTTCCAA", 0},
+		// the best matching adapter is removed in each round
+		{[]Adapter{{Sequence: smallRNA}, {Sequence: illumina}}, DefaultAdapterTrimOptions, "GGGGGGTGGAATTCTCGGAGATCGGAAGAGC", "GGGGGGTGGAATTCTCGG", 1},
+		{[]Adapter{{Sequence: smallRNA}, {Sequence: illumina}}, AdapterTrimOptions{ErrorRate: 0.1, MinOverlap: 3, Times: 2}, "GGGGGGTGGAATTCTCGGAGATCGGAAGAGC", "GGGGGG", 2},
+	}
+
+	for i, elem := range testSuite {
+		trimmer, err := NewAdapterTrimmer(elem.adapters, elem.opts)
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %v", i, err)
+		}
+		read := NewFASTQRead("@read", []rune(elem.read), "+", []rune(strings.Repeat("I", len(elem.read))))
+		trimmed, matches := trimmer.Trim(read)
+		if string(trimmed.Sequence) != elem.trimmed || len(matches) != elem.matches {
+			t.Errorf("test %d: expected %s with %d matches, but got %s with %+v", i, elem.trimmed, elem.matches, string(trimmed.Sequence), matches)
+		}
+		if len(trimmed.Encoded) != len(trimmed.Sequence) || len(trimmed.Decoded) != len(trimmed.Sequence) {
+			t.Errorf("test %d: expected the qualities to be trimmed with the sequence, but got %d and %d for %d bases", i,
+				len(trimmed.Encoded), len(trimmed.Decoded), len(trimmed.Sequence))
+		}
+	}
+}
+
+func TestAdapterMatch(t *testing.T) {
+	fmt.Println("testing the AdapterMatch of a partial adapter...")
+
+	trimmer, _ := NewAdapterTrimmer([]Adapter{{Name: "illumina", Sequence: NucleotideSequence("AGATCGGAAGAGC")}}, DefaultAdapterTrimOptions)
+	read := NewFASTQRead("@read", []rune("CCCCCCCCAGATC"), "+", []rune("ABCDEFGHIJKLM"))
+	trimmed, matches := trimmer.Trim(read)
+
+	if string(trimmed.Encoded) != "ABCDEFGH" {
+		t.Errorf("expected the qualities ABCDEFGH, but got %s", string(trimmed.Encoded))
+	}
+	if len(matches) != 1 {
+		t.Fatalf("expected 1 match, but got %+v", matches)
+	}
+	m := matches[0]
+	if m.Adapter != "illumina" || m.Type != ThreePrimeAdapter || m.ReadStart != 8 || m.ReadEnd != 13 ||
+		m.AdapterStart != 0 || m.AdapterEnd != 5 || m.Matches != 5 || m.Errors != 0 || string(m.Removed) != "AGATC" {
+		t.Errorf("unexpected match %+v", m)
+	}
+}
+
+// func NewAdapterTrimmer(adapters []Adapter, opts AdapterTrimOptions) (*AdapterTrimmer, error)
+func TestNewAdapterTrimmer(t *testing.T) {
+	fmt.Println("testing NewAdapterTrimmer()...")
+
+	testSuite := [][]Adapter{
+		nil,
+		{{Name: "empty"}},
+		{{Name: "unknown", Sequence: NucleotideSequence("ACGT"), Type: AdapterType(9)}},
+		{{Name: "half", Type: LinkedAdapter, Front: &Adapter{Sequence: NucleotideSequence("ACGT"), Type: FivePrimeAdapter}}},
+		{{Name: "backwards", Type: LinkedAdapter,
+			Front: &Adapter{Sequence: NucleotideSequence("ACGT"), Type: ThreePrimeAdapter},
+			Back:  &Adapter{Sequence: NucleotideSequence("ACGT"), Type: FivePrimeAdapter}}},
+	}
+
+	for i, adapters := range testSuite {
+		if _, err := NewAdapterTrimmer(adapters, DefaultAdapterTrimOptions); err == nil {
+			t.Errorf("test %d: expected an error for %+v", i, adapters)
+		}
+	}
+}
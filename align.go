@@ -10,7 +10,8 @@ lengths, or sequences which might only partially overlap.
 Aside from a generic semi-global alignment algorithm, there are also two usage specific
 implementations that allow gap penelties at either the 5'-end or 3'end of the query string,
 which is useful when looking for a substring that you know should be at one end or another of the
-subject string.
+subject string. SGAlign takes the free ends of each sequence individually, and Align also offers
+global (Needleman-Wunsch) and local (Smith-Waterman) alignments with the same scoring.
 
 	example:
 		3' Linker : GTGTCAGCACA
@@ -34,6 +35,8 @@ subject string.
 
 package gobioinfo
 
+import "unicode/utf8"
+
 //"fmt"
 
 /*
@@ -75,6 +78,16 @@ const (
 	gap                  // "-"
 )
 
+// The movement kept for each cell only needs the six bits above, so the top two bits
+// record whether the gap matrices I and J extend the gap of the cell before (rather than
+// opening one from H). The traceback needs them to stay in a gap matrix for the whole
+// length of a gap.
+const (
+	extendI  = 1 << 6
+	extendJ  = 1 << 7
+	moveBits = extendI - 1 // the movement chosen for H
+)
+
 // PairWiseAlignment creates an pairwise alignment structure
 type PairWiseAlignment struct {
 	Subject                 NucleotideSequence
@@ -87,6 +100,30 @@ type PairWiseAlignment struct {
 	GappedSubject           string
 	GappedQuery             string
 	AlignmentRepresentation string
+	// Score is the score of the alignment in the alignment matrices
+	Score int
+	// Strand is the strand of the subject aligned to. On the Reverse strand, Subject is
+	// the reverse complement of the subject given, and SubjectStart counts along it.
+	Strand Strand
+}
+
+// Strand is a strand of the subject of an alignment
+type Strand int
+
+// Strands
+const (
+	// Forward is the subject as it was given
+	Forward Strand = iota
+	// Reverse is the reverse complement of the subject
+	Reverse
+)
+
+// String returns "+" for the Forward strand and "-" for the Reverse strand
+func (s Strand) String() string {
+	if s == Reverse {
+		return "-"
+	}
+	return "+"
 }
 
 // PairWiseRepresentation is a convenience struct for printing and displaying a pairwise alignment
@@ -96,28 +133,11 @@ type PairWiseRepresentation struct {
 	Matches string
 }
 
-type matrixMovement struct {
-	Score  int
-	Origin int
-}
-
 type matrixPosition struct {
 	i int
 	j int
 }
 
-func max(list []matrixMovement) matrixMovement {
-
-	max := list[0]
-
-	for i := 1; i < len(list); i++ {
-		if list[i].Score > max.Score {
-			max = list[i]
-		}
-	}
-	return (max)
-}
-
 func maxInt(list []int) int {
 
 	max := list[0]
@@ -132,368 +152,378 @@ func maxInt(list []int) int {
 }
 
 func alignmentRepr(alignment PairWiseAlignment) PairWiseAlignment {
+	alignment, _ = appendAlignmentRepr(alignment, []byte(alignment.ExpandedCIGAR))
+	return (alignment)
+}
+
+// appendAlignmentRepr fills in the ExpandedCIGAR, gapped strings and lengths of the
+// alignment from the letters of the CIGAR in buf. The strings are appended to buf and
+// share one allocation, and buf is returned so that it can be used again.
+func appendAlignmentRepr(alignment PairWiseAlignment, buf []byte) (PairWiseAlignment, []byte) {
 
 	subject := alignment.Subject
 	query := alignment.Query
-	CIGAR := alignment.ExpandedCIGAR
+	CIGAR := buf
 	subjectStart := alignment.SubjectStart
 	queryStart := alignment.QueryStart
 
+	if len(CIGAR) == 0 {
+		return alignment, buf
+	}
+
 	//parse CIGAR
 
 	ins := 0
 	dels := 0
 
-	var subjectRepr string
-	var queryRepr string
-	var alignmentRepr string
-	if CIGAR == "" {
-		return (alignment)
+	// the gapped subject, then the gapped query and then the alignment representation
+	cigarEnd := len(buf)
+	for i := 0; i < len(CIGAR); i++ {
+		switch CIGAR[i] {
+		case 'm', 'x', 'n', 'i':
+			buf = appendBase(buf, subject[subjectStart+i-dels])
+		case 'j':
+			buf = append(buf, '-')
+			dels = dels + 1
+		}
 	}
+	subjectEnd := len(buf)
 	for i := 0; i < len(CIGAR); i++ {
-		subjectPosition := subjectStart + i - dels
-		queryPosition := queryStart + i - ins
-		switch string(CIGAR[i]) {
-		case "m":
-			subjectRepr = subjectRepr + string(subject[subjectPosition])
-			queryRepr = queryRepr + string(query[queryPosition])
-			alignmentRepr = alignmentRepr + "|"
-		case "x":
-			subjectRepr = subjectRepr + string(subject[subjectPosition])
-			queryRepr = queryRepr + string(query[queryPosition])
-			alignmentRepr = alignmentRepr + " "
-		case "n":
-			subjectRepr = subjectRepr + string(subject[subjectPosition])
-			queryRepr = queryRepr + string(query[queryPosition])
-			alignmentRepr = alignmentRepr + " "
-		case "i":
-			subjectRepr = subjectRepr + string(subject[subjectPosition])
-			queryRepr = queryRepr + "-"
-			alignmentRepr = alignmentRepr + " "
+		switch CIGAR[i] {
+		case 'm', 'x', 'n', 'j':
+			buf = appendBase(buf, query[queryStart+i-ins])
+		case 'i':
+			buf = append(buf, '-')
 			ins = ins + 1
-		case "j":
-			subjectRepr = subjectRepr + "-"
-			queryRepr = queryRepr + string(query[queryPosition])
-			alignmentRepr = alignmentRepr + " "
-			dels = dels + 1
 		}
-
 	}
-	alignment.GappedSubject = subjectRepr
-	alignment.GappedQuery = queryRepr
-	alignment.AlignmentRepresentation = alignmentRepr
+	queryEnd := len(buf)
+	for i := 0; i < len(CIGAR); i++ {
+		switch CIGAR[i] {
+		case 'm':
+			buf = append(buf, '|')
+		case 'x', 'n', 'i', 'j':
+			buf = append(buf, ' ')
+		}
+	}
+
+	joined := string(buf)
+	alignment.ExpandedCIGAR = joined[:cigarEnd]
+	alignment.GappedSubject = joined[cigarEnd:subjectEnd]
+	alignment.GappedQuery = joined[subjectEnd:queryEnd]
+	alignment.AlignmentRepresentation = joined[queryEnd:]
 
 	alignment.SubjectAlignLen = len(CIGAR) - dels
 	alignment.QueryAlignLen = len(CIGAR) - ins
 
-	return (alignment)
+	return alignment, buf
 }
 
-// alignment algorithm
-
-// SG5pAlign aligns...
-func (q NucleotideSequence) SG5pAlign(s NucleotideSequence) PairWiseAlignment {
-	return (q.sGAlign(s, "five"))
+// appendBase appends the UTF-8 encoding of a base to buf
+func appendBase(buf []byte, base rune) []byte {
+	if base >= 0 && base < utf8.RuneSelf {
+		return append(buf, byte(base))
+	}
+	return utf8.AppendRune(buf, base)
 }
 
-// SG3pAlign aligns...
-func (q NucleotideSequence) SG3pAlign(s NucleotideSequence) PairWiseAlignment {
-	return (q.sGAlign(s, "three"))
-}
+// AlignMode selects the kind of alignment carried out by Align
+type AlignMode int
 
-// Align applies a semi-global alignment algorithm to the query and subject sequences
-func (q NucleotideSequence) sGAlign(s NucleotideSequence, end string) PairWiseAlignment {
+// Alignment modes
+const (
+	// SemiGlobal alignments do not penalize gaps at the ends of the sequences chosen by
+	// AlignOptions.Ends, and end on the last row or column of the alignment matrix
+	SemiGlobal AlignMode = iota
+	// Global (Needleman-Wunsch) alignments cover both sequences from end to end
+	Global
+	// Local (Smith-Waterman) alignments cover the best scoring pair of subsequences
+	Local
+)
 
-	// get the length of the input strings
-	lenS := len(s)
+// FreeEnds selects which ends of each sequence may be left out of a semi-global
+// alignment without penalty
+type FreeEnds struct {
+	QueryStart   bool
+	QueryEnd     bool
+	SubjectStart bool
+	SubjectEnd   bool
+}
 
-	lenI := lenS + 1
+// Free end presets
+var (
+	// AllEndsFree allows the sequences to overlap in any way, or one to be contained
+	// within the other
+	AllEndsFree = FreeEnds{QueryStart: true, QueryEnd: true, SubjectStart: true, SubjectEnd: true}
+	// ThreePrimeEnds anchors the start of the subject within the query, as used by SG3pAlign
+	ThreePrimeEnds = FreeEnds{QueryStart: true, QueryEnd: true, SubjectEnd: true}
+	// FivePrimeEnds anchors the start of the query within the subject, as used by SG5pAlign
+	FivePrimeEnds = FreeEnds{SubjectStart: true, QueryEnd: true, SubjectEnd: true}
+)
 
-	lenQ := len(q)
+// AlignOptions holds the settings for the alignment algorithms. The zero value is a
+// semi-global alignment with all ends free, using DefaultScoring.
+type AlignOptions struct {
+	Scoring Scoring
+	Mode    AlignMode
+	// Ends selects the free ends of a SemiGlobal alignment; the zero value frees all
+	// four (an alignment with no free ends is a Global one)
+	Ends FreeEnds
+	// Ties chooses between equally scoring alignments
+	Ties TieBreak
+	// BothStrands aligns the query to the reverse complement of the subject as well, and
+	// returns whichever alignment scores higher (the forward one if they tie). It is
+	// ignored by BandedAlign, as a band is set for one strand, and by AlignScore,
+	// AlignMatrices, CoOptimalAlignments and TopAlignments.
+	BothStrands bool
+}
 
-	lenJ := lenQ + 1
+// scoring returns the Scoring to use, falling back to DefaultScoring when none was set
+func (o AlignOptions) scoring() Scoring {
+	if o.Scoring == (Scoring{}) {
+		return DefaultScoring
+	}
+	return o.Scoring
+}
 
-	const (
-		h             = 6 //gap opening penalty
-		g             = 3 //gap extension penalty
-		matchScore    = 3
-		mismatchScore = -4
-	)
+// ends returns the free ends to use for a SemiGlobal alignment
+func (o AlignOptions) ends() FreeEnds {
+	if o.Ends == (FreeEnds{}) {
+		return AllEndsFree
+	}
+	return o.Ends
+}
 
-	// TODO: add visualization of the alignment matrix
+// firstOptions returns the first of a set of optional AlignOptions, or the defaults
+func firstOptions(opts []AlignOptions) AlignOptions {
+	if len(opts) == 0 {
+		return AlignOptions{}
+	}
+	return opts[0]
+}
 
-	//   subjectString
+// onBothStrands aligns to the subject with align, and to its reverse complement as well
+// if both is set, returning the higher scoring alignment
+func onBothStrands(s NucleotideSequence, both bool, align func(s NucleotideSequence) PairWiseAlignment) PairWiseAlignment {
+	forward := align(s)
+	if !both {
+		return forward
+	}
+	reverse := align(s.ReverseComplement())
+	reverse.Strand = Reverse
+	if reverse.Score > forward.Score {
+		return reverse
+	}
+	return forward
+}
 
-	//TODO: optimization - use a matrix (github.com/gonum/matrix/mat64) instead of a [][]int
-	// 	this removes half off all the matric/array lookups
+// alignment algorithm
 
-	// set up matrices: H is the match matrix, I is the matrix of gaps in the *i* dimentions (eg
-	// along the subject), J is the matric for gaps in the *j* dimension (eg along the query), and
-	// D is the matrix of which direction (vector) was chosen to fill the H matrix.
-	//
-	// create the outer arrays (j dimension = position along query)
-	H := make([][]int, lenJ)
-	I := make([][]int, lenJ)
-	J := make([][]int, lenJ)
-	D := make([][]int, lenJ)
+// SG5pAlign aligns the query to the subject, with gaps penalized at the 5'-end of the
+// query but not of the subject. An AlignOptions may be given to change the scoring.
+func (q NucleotideSequence) SG5pAlign(s NucleotideSequence, opts ...AlignOptions) PairWiseAlignment {
+	o := firstOptions(opts)
+	return onBothStrands(s, o.BothStrands, func(s NucleotideSequence) PairWiseAlignment {
+		return q.align(s, SemiGlobal, FivePrimeEnds, o.scoring(), o.Ties, nil)
+	})
+}
 
-	// create the inner arrays (i dimension = position along subject)
-	for index := range H {
-		H[index] = make([]int, lenI)
-		J[index] = make([]int, lenI)
-		I[index] = make([]int, lenI)
-		D[index] = make([]int, lenI)
-	}
+// SG3pAlign aligns the query to the subject, with gaps penalized at the 5'-end of the
+// subject but not of the query, so that the subject is anchored by its start within the
+// query, as a 3' linker is in a read. An AlignOptions may be given to change the scoring.
+func (q NucleotideSequence) SG3pAlign(s NucleotideSequence, opts ...AlignOptions) PairWiseAlignment {
+	o := firstOptions(opts)
+	return onBothStrands(s, o.BothStrands, func(s NucleotideSequence) PairWiseAlignment {
+		return q.align(s, SemiGlobal, ThreePrimeEnds, o.scoring(), o.Ties, nil)
+	})
+}
 
-	//TODO:optimization , remove all of the casting here, and do comparisons against constants
-	matcher := func(i int, j int) matrixMovement {
+// SGAlign aligns the query to the subject with no penalty for gaps at the ends chosen by
+// the Ends of an AlignOptions, if one is given, or at any end if none are chosen
+func (q NucleotideSequence) SGAlign(s NucleotideSequence, opts ...AlignOptions) PairWiseAlignment {
+	o := firstOptions(opts)
+	return onBothStrands(s, o.BothStrands, func(s NucleotideSequence) PairWiseAlignment {
+		return q.align(s, SemiGlobal, o.ends(), o.scoring(), o.Ties, nil)
+	})
+}
 
-		var returnValue matrixMovement
-		switch {
+// GlobalAlign aligns the whole of the query to the whole of the subject
+// (Needleman-Wunsch with affine gaps). An AlignOptions may be given to change the scoring.
+func (q NucleotideSequence) GlobalAlign(s NucleotideSequence, opts ...AlignOptions) PairWiseAlignment {
+	o := firstOptions(opts)
+	return onBothStrands(s, o.BothStrands, func(s NucleotideSequence) PairWiseAlignment {
+		return q.align(s, Global, FreeEnds{}, o.scoring(), o.Ties, nil)
+	})
+}
 
-		case s[i-1] == q[j-1]:
-			// if the position is a match
-			returnValue.Score = H[j-1][i-1] + matchScore
-			returnValue.Origin = match
+// LocalAlign finds the best scoring alignment between any part of the query and any part
+// of the subject (Smith-Waterman with affine gaps). An AlignOptions may be given to change
+// the scoring.
+func (q NucleotideSequence) LocalAlign(s NucleotideSequence, opts ...AlignOptions) PairWiseAlignment {
+	o := firstOptions(opts)
+	return onBothStrands(s, o.BothStrands, func(s NucleotideSequence) PairWiseAlignment {
+		return q.align(s, Local, FreeEnds{}, o.scoring(), o.Ties, nil)
+	})
+}
 
-		case q[j-1] == ntN:
-			// if the base is undefined treat it is a neutral
-			returnValue.Score = H[j-1][i-1]
-			returnValue.Origin = neutral
+// Align aligns the query to the subject with the mode, free ends and scoring of an
+// AlignOptions, or semi-globally with the DefaultScoring if none is given
+func (q NucleotideSequence) Align(s NucleotideSequence, opts ...AlignOptions) PairWiseAlignment {
+	o := firstOptions(opts)
+	return onBothStrands(s, o.BothStrands, func(s NucleotideSequence) PairWiseAlignment {
+		return q.align(s, o.Mode, o.ends(), o.scoring(), o.Ties, nil)
+	})
+}
 
-		default:
-			// otherwise it is a mismatch
-			returnValue.Score = H[j-1][i-1] + mismatchScore
-			returnValue.Origin = mismatch
-		}
-		return (returnValue)
+// negInf stands in for minus infinity in the alignment matrices; it is small enough to
+// never be chosen, but far enough from the int limits to have penalties subtracted
+const negInf = -1 << 30
+
+// modeEdges returns the ends of each sequence which can be skipped for free in an
+// alignment mode, and the value the gap matrices start from along the edges
+func modeEdges(mode AlignMode, ends FreeEnds) (FreeEnds, int) {
+	switch mode {
+	case Global:
+		ends = FreeEnds{}
+	case Local:
+		ends = AllEndsFree
 	}
 
-	traceback := func(start matrixPosition) matrixPosition {
-		currentVector := D[start.j][start.i]
-		var nextI int
-		var nextJ int
-		switch currentVector {
-		case match:
-			nextI = start.i - 1
-			nextJ = start.j - 1
-		case mismatch:
-			nextI = start.i - 1
-			nextJ = start.j - 1
-		case neutral:
-			nextI = start.i - 1
-			nextJ = start.j - 1
-		case insI:
-			nextI = start.i - 1
-			nextJ = start.j
-		case insJ:
-			nextI = start.i
-			nextJ = start.j - 1
-		}
+	// in semi-global alignments the gap matrices start from 0 along the free edges (and at
+	// the corner), so a gap next to one is charged as an extension; global and local
+	// alignments use the standard (Gotoh) boundary where a gap can only be opened from H
+	edgeGap := 0
+	if mode != SemiGlobal {
+		edgeGap = negInf
+	}
+	return ends, edgeGap
+}
 
-		nextPos := matrixPosition{i: nextI, j: nextJ}
+// align applies a global, local or semi-global alignment algorithm to the query and
+// subject sequences. For SemiGlobal alignments only the ends in the FreeEnds are free. If
+// band is not nil only the cells within the band are filled.
+func (q NucleotideSequence) align(s NucleotideSequence, mode AlignMode, ends FreeEnds, sc Scoring, ties TieBreak, band *Band) PairWiseAlignment {
+	return newAligner(q, s, mode, ends, sc, ties, band).align()
+}
 
-		return (nextPos)
-	}
+// align carries out the alignment an aligner has been set up for
+func (a *aligner) align() PairWiseAlignment {
 
-	// fill matrices
-
-	for j := range H {
-		for i := range H[j] {
-			switch {
-			case i != 0 && j != 0:
-				// if we are not in the first column or first row
-
-				//fill in I
-				I[j][i] = maxInt([]int{
-					H[j][i-1] - h,
-					I[j][i-1] - g,
-				})
-
-				// fill in J
-				J[j][i] = maxInt([]int{
-					H[j-1][i] - h,
-					J[j-1][i] - g,
-				})
-
-				// fill in H and D
-				bestMove := max([]matrixMovement{
-					matcher(i, j),
-					matrixMovement{I[j][i], insI},
-					matrixMovement{J[j][i], insJ},
-				})
-				H[j][i] = bestMove.Score
-				D[j][i] = bestMove.Origin
-				//fmt.Println(bestMove.Score, bestMove.Origin)
-
-			case i == 0 && j == 0:
-				// if we are in the first position (0,0)
-				H[j][i] = 0
-				I[j][i] = 0
-				J[i][j] = 0
-				D[i][j] = gap
-
-			case i == 0 && j != 0:
-				// if we are in the first column
-				switch {
-				case end == "three":
-					// if we are conducting a three-prime bias'ed semi-global alignment we will
-					// put all zeros
-					H[j][i] = 0
-					I[j][i] = 0
-					J[j][i] = 0
-					D[j][i] = gap
-				case end == "five":
-					// if we are conducting a five-prim bias'ed semi-global alignment we will
-					// fill in the insertions along J matrix, and the H and D matrices accordingly
-					I[j][i] = 0
-
-					// fill in J
-					J[j][i] = maxInt([]int{
-						H[j-1][i] - h,
-						J[j-1][i] - g,
-					})
-
-					// fill in H and D
-					bestMove := max([]matrixMovement{
-						// matrixMovement{I[j][i], "i"},
-						matrixMovement{J[j][i], insJ},
-					})
-					H[j][i] = bestMove.Score
-					D[j][i] = bestMove.Origin
-				}
-
-			case i != 0 && j == 0:
-				switch {
-				case end == "three":
-
-					I[j][i] = maxInt([]int{
-						H[j][i-1] - h,
-						I[j][i-1] - g,
-					})
-
-					// fill in J
-					J[j][i] = 0
-
-					// fill in H and D
-					bestMove := max([]matrixMovement{
-						matrixMovement{I[j][i], insI},
-						// matrixMovement{J[j][i], "j"},
-					})
-					H[j][i] = bestMove.Score
-					D[j][i] = bestMove.Origin
-
-				case end == "five":
-					H[j][i] = 0
-					I[j][i] = 0
-					J[j][i] = 0
-					D[j][i] = gap
-				}
-
-			}
+	// get the length of the input strings
+	lenI := len(a.s) + 1
+
+	lenJ := len(a.q) + 1
+
+	// D is the matrix of which direction (vector) was chosen to fill each cell, held row
+	// by row (j dimension = position along query, i dimension = position along subject).
+	// Each row keeps stride cells from column first(j): the whole row, or with a band
+	// only the cells of the band, so that memory grows with the width of the band rather
+	// than the length of the subject. The first row and column are kept in rowD and colD.
+	stride := lenI
+	first := func(j int) int { return 0 }
+	if a.band != nil && 2*a.band.Width+1 < lenI {
+		stride = 2*a.band.Width + 1
+		if stride < 0 {
+			stride = 0
 		}
+		first = func(j int) int { return j + a.band.Offset - a.band.Width }
+	}
 
+	// long sequences are aligned without holding the whole matrices in memory
+	if lenI > 1 && lenJ > 1 && stride*lenJ > linearMemoryCells {
+		return a.alignLinear()
 	}
 
-	// create traceback
+	a.edges()
 
-	//find max score in the last row or column
+	a.D = growBytes(a.D, stride*lenJ)
+	D := a.D
 
-	maxScore := H[lenJ-1][lenI-1]
-	maxPosition := matrixPosition{
-		i: lenI - 1,
-		j: lenJ - 1,
-	}
+	// fill the matrices, keeping D, and find the max score: the bottom right corner for a
+	// global alignment, the last row or column (when the end of the subject or query is
+	// free) for a semi-global alignment, and anywhere in the matrix for a local alignment
+	maxPosition, maxScore := a.findEnd(func(j int, row []uint8) {
+		lo, hi := first(j), first(j)+stride-1
+		if lo < 1 {
+			lo = 1
+		}
+		if hi > lenI-1 {
+			hi = lenI - 1
+		}
+		if lo <= hi {
+			copy(D[j*stride+lo-first(j):], row[lo:hi+1])
+		}
+	})
 
-	for i := 0; i < lenI; i++ {
-		if H[lenJ-1][i] > maxScore {
-			maxScore = H[lenJ-1][i]
-			maxPosition.i = i
-			maxPosition.j = lenJ - 1
+	// movement returns the movements of a cell, which are gap outside of the band
+	movement := func(p matrixPosition) uint8 {
+		switch {
+		case p.j == 0:
+			return a.rowD[p.i]
+		case p.i == 0:
+			return a.colD[p.j]
 		}
-		//	fmt.Println("i: ", i)
-		//	fmt.Println("H[lenJ-1][i]: ", H[lenJ-1][i])
-	}
-	for j := 0; j < lenJ; j++ {
-		if H[j][lenI-1] > maxScore {
-			maxScore = H[j][lenI-1]
-			maxPosition.i = lenI - 1
-			maxPosition.j = j
+		k := p.i - first(p.j)
+		if k < 0 || k >= stride {
+			return gap
 		}
+		return D[p.j*stride+k]
 	}
 
-	// fmt.Println("max score: ", maxScore)
-	// fmt.Println("maxPosition.i: ", maxPosition.i)
-	// fmt.Println("maxPosition.j: ", maxPosition.j)
-
-	//fmt.Println(string("max position"), maxPosition.i, maxPosition.j)
-
 	//build reverse cigar string
 
-	var currentPosition matrixPosition
-
-	var revCIGAR []int
-
-	completedTraceback := false
+	// follow the movements back from the end through H and the gap matrices, until the
+	// traceback reaches a cell where an alignment may start: (0,0), the first row or column
+	// where that end is free, or a zero cell of a local alignment. Gaps along a penalized
+	// first row or column are part of the alignment.
+	position, state := maxPosition, inH
+	for {
+		move, next, nextState, start := a.traceStep(position, state, movement(position))
+		if start {
+			break
+		}
+		if move != 0 {
+			a.revCIGAR = append(a.revCIGAR, int(move))
+			a.last = position
+		}
+		position, state = next, nextState
+	}
 
-	currentPosition = maxPosition
-	var nextPosition matrixPosition
+	// there is no alignment if the traceback starts where it ends
+	if len(a.revCIGAR) == 0 {
+		return a.alignment(nil, maxPosition, maxScore)
+	}
+	return a.alignment(a.revCIGAR, a.last, maxScore)
+}
 
-	// check to make sure you don't start a traceback at a i=0 or j=0 position
-	// (which arises when there essentially is no alignment and the max alignment score is zero)
-	if currentPosition.i != 0 && currentPosition.j != 0 {
-		for completedTraceback == false {
+// newPairWiseAlignment builds the alignment from a traceback: revCIGAR holds the
+// movements from the end of the alignment back to its start, currentPosition is the last
+// cell the traceback visited and score is the score of the cell it started from. The
+// strings of the alignment are built in buf, which is returned to be used again.
+func newPairWiseAlignment(q NucleotideSequence, s NucleotideSequence, revCIGAR []int, currentPosition matrixPosition, score int, buf []byte) (PairWiseAlignment, []byte) {
 
-			currentVector := D[currentPosition.j][currentPosition.i]
-			revCIGAR = append(revCIGAR, currentVector)
-			nextPosition = traceback(currentPosition)
+	// create an forward cigar, at the start of buf
 
-			// check to see if we have reach the top or left of the matrix
-			if nextPosition.i == 0 || nextPosition.j == 0 {
-				completedTraceback = true
-			} else {
-				currentPosition = nextPosition
-			}
-		}
-	} else {
-		revCIGAR = nil
-	}
-	// fmt.Println("current position", currentPosition)
-	// fmt.Println(revCIGAR)
-
-	// create an forward cigar
-
-	var CIGAR string
-
-	// this check protects against the situation where not alignment takes place
-	if revCIGAR != nil {
-		for i := range revCIGAR {
-			nextVector := revCIGAR[len(revCIGAR)-1-i]
-			var nextLetter string
-			switch {
-			case nextVector == match:
-				nextLetter = "m"
-			case nextVector == mismatch:
-				nextLetter = "x"
-			case nextVector == neutral:
-				nextLetter = "n"
-			case nextVector == insI:
-				nextLetter = "i"
-			case nextVector == insJ:
-				nextLetter = "j"
-			case nextVector == gap:
-				nextLetter = "-"
-			}
-			CIGAR += nextLetter
+	buf = buf[:0]
+	for i := range revCIGAR {
+		nextVector := revCIGAR[len(revCIGAR)-1-i]
+		switch {
+		case nextVector == match:
+			buf = append(buf, 'm')
+		case nextVector == mismatch:
+			buf = append(buf, 'x')
+		case nextVector == neutral:
+			buf = append(buf, 'n')
+		case nextVector == insI:
+			buf = append(buf, 'i')
+		case nextVector == insJ:
+			buf = append(buf, 'j')
+		case nextVector == gap:
+			buf = append(buf, '-')
 		}
-	} else {
-		CIGAR = ""
-		// fmt.Println("here")
 	}
 
-	// fmt.Println(CIGAR)
-
-	// TODO: create new alignment object
+	CIGAR := buf
 
 	// cigar start = currentPosition
 	// cigar end = maxPosition
@@ -503,12 +533,12 @@ func (q NucleotideSequence) sGAlign(s NucleotideSequence, end string) PairWiseAl
 
 	queryStart := currentPosition.j - 1
 
-	if CIGAR != "" {
+	if len(CIGAR) > 0 {
 		switch {
-		case string(CIGAR[0]) == "i":
+		case CIGAR[0] == 'i':
 			subjectStart = currentPosition.i - 1
 			queryStart = currentPosition.j
-		case string(CIGAR[0]) == "j":
+		case CIGAR[0] == 'j':
 			subjectStart = currentPosition.i
 			queryStart = currentPosition.j - 1
 
@@ -520,35 +550,31 @@ func (q NucleotideSequence) sGAlign(s NucleotideSequence, end string) PairWiseAl
 
 	if currentPosition.i != 0 || currentPosition.j != 0 {
 		newAlignment = PairWiseAlignment{
-			Subject:       s,
-			Query:         q,
-			ExpandedCIGAR: CIGAR,
-			SubjectStart:  subjectStart,
-			QueryStart:    queryStart,
+			Subject:      s,
+			Query:        q,
+			SubjectStart: subjectStart,
+			QueryStart:   queryStart,
 		}
 	} else {
 		newAlignment = PairWiseAlignment{
-			Subject:       s,
-			Query:         q,
-			ExpandedCIGAR: CIGAR,
+			Subject: s,
+			Query:   q,
 		}
 	}
 
-	newAlignment = alignmentRepr(newAlignment)
-
-	// TODO: create print method for alignment object
-
-	// TODO: resolve same scores on traceback
+	newAlignment.Score = score
 
 	// return the new alignment object
-	return (newAlignment)
+	return appendAlignmentRepr(newAlignment, buf)
 }
 
-// TODO add a string method for alignment
-
-// func(a *PairWiseAlignment) String {
-//
-// }
+// alignment builds the alignment from a traceback as newPairWiseAlignment does, reusing
+// the buffer of the aligner for its strings
+func (a *aligner) alignment(revCIGAR []int, currentPosition matrixPosition, score int) PairWiseAlignment {
+	var alignment PairWiseAlignment
+	alignment, a.repr = newPairWiseAlignment(a.q, a.s, revCIGAR, currentPosition, score, a.repr)
+	return alignment
+}
 
 // func (a *PairWiseAlignment) GetSubjLen() int {}
 // func (a *PairWiseAlignment) GetSubjLen() int {}
@@ -3,6 +3,9 @@ package gobioinfo
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"math/rand"
+	"reflect"
 	"testing"
 )
 
@@ -51,10 +54,10 @@ B@CDFFFFHHDDFHHIJJJIIIDGHBFGFHIGHGHG@EGHJHH
 	var testReads []FASTQRead
 	for {
 		newRead, err := scanner.NextRead()
-		if err != nil {
-			if err.Error() == "EOF" {
-				break
-			}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal("unexpected error reading test data: ", err)
 		}
 		//	fmt.Println(newRead.DNASequence.Sequence)
 		testReads = append(testReads, newRead)
@@ -77,7 +80,7 @@ B@CDFFFFHHDDFHHIJJJIIIDGHBFGFHIGHGHG@EGHJHH
 		"mmmmmmmmmmmmmmmmmmmmmmmmmmmmmmmmmmmmmmmmm",
 		"mmmmmmmmmmmmmmmmmmmmmmmmmmmmmmmmmmmmmmmmm",
 		"mmmmmmmmmmmmmmmmmmmmmmmmmmm",
-		"immmxxmjmmmjmmjxmmmmmjmmxmimimmimiimmxmmmmxxm",
+		"immmxxmjmmmjmmjxmmmmmjmmxmimimmmiiimmxmmmmxxm",
 		"",
 	}
 
@@ -162,3 +165,275 @@ B@CDFFFFHHDDFHHIJJJIIIDGHBFGFHIGHGHG@EGHJHH
 }
 
 // func alignmentRepr(alignment PairWiseAlignment) PairWiseAlignment {}
+
+// func (q NucleotideSequence) SG3pAlign(s NucleotideSequence, opts ...AlignOptions) PairWiseAlignment {}
+func TestSGAlignScoring(t *testing.T) {
+	fmt.Println("testing SGAlign scoring options...")
+
+	noGaps := Scoring{Match: 1, Mismatch: -1, GapOpen: 20, GapExtend: 20}
+
+	type testGroup struct {
+		alignment          PairWiseAlignment
+		expectedCIGAR      string
+		expectedQueryStart int
+	}
+
+	query := NucleotideSequence("TTTTACGTACGTACGT")
+	subject := NucleotideSequence("ACGTAACGTACGTGGGG")
+	withN := NucleotideSequence("ACGTNNGT")
+	withoutN := NucleotideSequence("ACGTACGT")
+
+	testSuite := []testGroup{
+		{query.SG3pAlign(subject), "mmmmimmmmmmmm", 4},
+		{query.SG3pAlign(subject, AlignOptions{}), "mmmmimmmmmmmm", 4},
+		{query.SG3pAlign(subject, AlignOptions{Scoring: DefaultScoring}), "mmmmimmmmmmmm", 4},
+		{query.SG3pAlign(subject, AlignOptions{Scoring: noGaps}), "mmmm", 12},
+		{query.SGAlign(subject, AlignOptions{Scoring: noGaps}), "xxmxmmmmmmmmxxmx", 0},
+		{withN.SG3pAlign(withoutN), "mmmmnnmm", 0},
+	}
+
+	for _, elem := range testSuite {
+		if elem.expectedCIGAR != elem.alignment.ExpandedCIGAR || elem.expectedQueryStart != elem.alignment.QueryStart {
+			t.Error(
+				"expected: \t",
+				elem.expectedCIGAR, elem.expectedQueryStart,
+				"\nbut got: \t\t",
+				elem.alignment.ExpandedCIGAR, elem.alignment.QueryStart,
+				"\n",
+			)
+		}
+	}
+}
+
+// func (q NucleotideSequence) SGAlign(s NucleotideSequence, opts ...AlignOptions) PairWiseAlignment {}
+// func (q NucleotideSequence) GlobalAlign(s NucleotideSequence, opts ...AlignOptions) PairWiseAlignment {}
+// func (q NucleotideSequence) LocalAlign(s NucleotideSequence, opts ...AlignOptions) PairWiseAlignment {}
+// func (q NucleotideSequence) Align(s NucleotideSequence, opts ...AlignOptions) PairWiseAlignment {}
+func TestAlignDefaultOptions(t *testing.T) {
+	fmt.Println("testing the alignment functions without AlignOptions...")
+
+	query := NucleotideSequence("TTTTACGTACGTACGT")
+	subject := NucleotideSequence("ACGTAACGTACGTGGGG")
+
+	type testPair struct {
+		name        string
+		withoutOpts interface{}
+		withOpts    interface{}
+	}
+
+	banded, _ := query.BandedAlign(subject, Band{Width: 8})
+	bandedOpts, _ := query.BandedAlign(subject, Band{Width: 8}, AlignOptions{})
+
+	testSuite := []testPair{
+		{"SGAlign", query.SGAlign(subject), query.SGAlign(subject, AlignOptions{})},
+		{"GlobalAlign", query.GlobalAlign(subject), query.GlobalAlign(subject, AlignOptions{})},
+		{"LocalAlign", query.LocalAlign(subject), query.LocalAlign(subject, AlignOptions{})},
+		{"Align", query.Align(subject), query.Align(subject, AlignOptions{})},
+		{"BandedAlign", banded, bandedOpts},
+		{"AlignScore", query.AlignScore(subject), query.AlignScore(subject, AlignOptions{})},
+		{"AlignMatrices", query.AlignMatrices(subject).Alignment, query.AlignMatrices(subject, AlignOptions{}).Alignment},
+		{"CoOptimalAlignments", query.CoOptimalAlignments(subject, 0), query.CoOptimalAlignments(subject, 0, AlignOptions{})},
+		{"TopAlignments", query.TopAlignments(subject, 2), query.TopAlignments(subject, 2, AlignOptions{})},
+		{"Aligner", NewAligner(query).Align(subject), NewAligner(query, AlignOptions{}).Align(subject)},
+	}
+
+	for i, elem := range testSuite {
+		if !reflect.DeepEqual(elem.withoutOpts, elem.withOpts) {
+			t.Errorf("test %d: expected %s to default to the zero AlignOptions, giving %+v, but got %+v", i, elem.name, elem.withOpts, elem.withoutOpts)
+		}
+	}
+}
+
+// func (q NucleotideSequence) Align(s NucleotideSequence, opts ...AlignOptions) PairWiseAlignment {}
+func TestAlignModes(t *testing.T) {
+	fmt.Println("testing global, local and semi-global alignment modes...")
+
+	type testGroup struct {
+		alignment       PairWiseAlignment
+		expectedSubject string
+		expectedQuery   string
+		queryStart      int
+		subjectStart    int
+	}
+
+	seq := func(s string) NucleotideSequence { return NucleotideSequence(s) }
+
+	testSuite := []testGroup{
+		{seq("ACGTACGT").GlobalAlign(seq("ACGTTACGT"), AlignOptions{}),
+			"ACGTTACGT", "ACG-TACGT", 0, 0},
+		{seq("GGACGTACGT").GlobalAlign(seq("ACGTACGTCC"), AlignOptions{}),
+			"--ACGTACGTCC", "GGACGTACGT--", 0, 0},
+		{seq("GGACGTACGT").Align(seq("ACGTACGTCC"), AlignOptions{Mode: Global}),
+			"--ACGTACGTCC", "GGACGTACGT--", 0, 0},
+		{seq("").GlobalAlign(seq("ACG"), AlignOptions{}),
+			"ACG", "---", 0, 0},
+		{seq("TTTTACGTACGTTTT").LocalAlign(seq("GGGACGTACGGG"), AlignOptions{}),
+			"ACGTACG", "ACGTACG", 4, 3},
+		{seq("TTTT").LocalAlign(seq("GGGG"), AlignOptions{}),
+			"", "", 0, 0},
+		{seq("ACGTACGTAAAA").SGAlign(seq("CCCCACGTACGT"), AlignOptions{Ends: FreeEnds{QueryEnd: true, SubjectStart: true}}),
+			"ACGTACGT", "ACGTACGT", 0, 4},
+		{seq("ACGTACGTAAAA").Align(seq("CCCCACGTACGT"), AlignOptions{}),
+			"ACGTACGT", "ACGTACGT", 0, 4},
+		// the start of the subject is penalized, so its first base is kept as a gap
+		{seq("ACGTACGTAAAA").SGAlign(seq("CACGTACGTAAAAGG"), AlignOptions{Ends: FreeEnds{QueryStart: true, SubjectEnd: true}}),
+			"CACGTACGTAAAA", "-ACGTACGTAAAA", 0, 0},
+	}
+
+	for i, elem := range testSuite {
+		a := elem.alignment
+		if a.GappedSubject != elem.expectedSubject || a.GappedQuery != elem.expectedQuery ||
+			a.QueryStart != elem.queryStart || a.SubjectStart != elem.subjectStart {
+			t.Errorf("test %d: expected %q/%q from %d/%d, but got %q/%q from %d/%d", i,
+				elem.expectedSubject, elem.expectedQuery, elem.subjectStart, elem.queryStart,
+				a.GappedSubject, a.GappedQuery, a.SubjectStart, a.QueryStart)
+		}
+	}
+}
+
+// func (q NucleotideSequence) Align(s NucleotideSequence, opts ...AlignOptions) PairWiseAlignment {}
+func TestAlignBothStrands(t *testing.T) {
+	fmt.Println("testing Align() on both strands...")
+
+	seq := func(s string) NucleotideSequence { return NucleotideSequence(s) }
+	adapter := seq("AGATCGGAAGAGC")
+
+	type testGroup struct {
+		alignment    PairWiseAlignment
+		strand       Strand
+		subjectStart int
+		cigar        string
+	}
+
+	testSuite := []testGroup{
+		// the adapter on the forward strand
+		{adapter.LocalAlign(seq("TTTTAGATCGGAAGAGCTTTT"), AlignOptions{BothStrands: true}), Forward, 4, "mmmmmmmmmmmmm"},
+		// and on the reverse strand, with positions along the reverse complement
+		{adapter.LocalAlign(seq("AAAGCTCTTCCGATCTAAAAAA"), AlignOptions{BothStrands: true}), Reverse, 6, "mmmmmmmmmmmmm"},
+		{adapter.LocalAlign(seq("AAAGCTCTTCCGATCTAAAAAA"), AlignOptions{}), Forward, 11, "mmmm"},
+		// the reverse complement of a linker found in a read
+		{seq("AAAGCTCTTCCGATCTAAAAAA").SG3pAlign(adapter, AlignOptions{BothStrands: true}), Reverse, 0, "mmmmmmmmmmmmm"},
+		// a tie goes to the forward strand
+		{seq("ACGT").Align(seq("ACGT"), AlignOptions{BothStrands: true}), Forward, 0, "mmmm"},
+	}
+
+	for i, elem := range testSuite {
+		a := elem.alignment
+		if a.Strand != elem.strand || a.SubjectStart != elem.subjectStart || a.ExpandedCIGAR != elem.cigar {
+			t.Errorf("test %d: expected %s at %d on the %s strand, but got %s at %d on the %s strand", i,
+				elem.cigar, elem.subjectStart, elem.strand, a.ExpandedCIGAR, a.SubjectStart, a.Strand)
+		}
+	}
+}
+
+// cigarScore scores an alignment from its ExpandedCIGAR: each pair of bases as
+// Scoring.pair does, and each gap as GapOpen + (k-1)*GapExtend, except that in a
+// semi-global alignment a gap starting from a free edge or the corner of the matrices is
+// charged GapExtend for every position, as the gap matrices start from 0 there
+func cigarScore(a PairWiseAlignment, mode AlignMode, ends FreeEnds, sc Scoring) int {
+	ends, _ = modeEdges(mode, ends)
+	semiGlobal := mode == SemiGlobal
+	score := 0
+	i, j := a.SubjectStart, a.QueryStart
+	var previous byte
+	for k := 0; k < len(a.ExpandedCIGAR); k++ {
+		column := a.ExpandedCIGAR[k]
+		switch column {
+		case 'i':
+			if column == previous || (semiGlobal && i == 0 && (j == 0 || ends.QueryStart)) {
+				score -= sc.GapExtend
+			} else {
+				score -= sc.GapOpen
+			}
+			i++
+		case 'j':
+			if column == previous || (semiGlobal && j == 0 && (i == 0 || ends.SubjectStart)) {
+				score -= sc.GapExtend
+			} else {
+				score -= sc.GapOpen
+			}
+			j++
+		default:
+			pair, _ := sc.pair(a.Subject[i], a.Query[j])
+			score += pair
+			i++
+			j++
+		}
+		previous = column
+	}
+	return score
+}
+
+// func (q NucleotideSequence) SG3pAlign(s NucleotideSequence, opts ...AlignOptions) PairWiseAlignment {}
+func TestSG3pAlignAffineGaps(t *testing.T) {
+	fmt.Println("testing that SG3pAlign() traces gaps through the gap matrices...")
+
+	query := NucleotideSequence("CACAGGGAGGACGATGCGGAGGAGAAGACCACATATGTGAAGGCCCCTGGTTGACTGGTTGTGGGCTCAGCTGACCAGCTGGGCTTGCCTGCTGCAGGCG")
+	subject := NucleotideSequence("GTGTCAGTCACTTCCAGCGGTCGTATGCCGTCTTCTGCTTG")
+	a := query.SG3pAlign(subject)
+
+	// the gap of three scored in I (one opening and two extensions) is traced back as one
+	// gap. Dropping back into H after each step of a gap, as the traceback used to, gave
+	// "...mimimmimiimm..." instead: a gap of one and a gap of two, which score 3 less than
+	// the alignment's Score
+	old := a
+	old.ExpandedCIGAR = "immmxxmjmmmjmmjxmmmmmjmmxmimimmimiimmxmmmmxxm"
+	if a.ExpandedCIGAR != "immmxxmjmmmjmmjxmmmmmjmmxmimimmmiiimmxmmmmxxm" || a.Score != 5 ||
+		cigarScore(a, SemiGlobal, ThreePrimeEnds, DefaultScoring) != a.Score {
+		t.Errorf("expected ...mimimmmiiimm... scoring 5, but got %s scoring %d (%d)",
+			a.ExpandedCIGAR, cigarScore(a, SemiGlobal, ThreePrimeEnds, DefaultScoring), a.Score)
+	}
+	if score := cigarScore(old, SemiGlobal, ThreePrimeEnds, DefaultScoring); score != 2 {
+		t.Errorf("expected the old traceback to score 2, but got %d", score)
+	}
+}
+
+// func (q NucleotideSequence) Align(s NucleotideSequence, opts ...AlignOptions) PairWiseAlignment {}
+func TestAlignScoreMatchesCIGAR(t *testing.T) {
+	fmt.Println("testing that Align() returns alignments scoring their Score...")
+
+	// trace through tiny blocks when the linear memory algorithm is used
+	defer func(cells int) { linearBaseCells = cells }(linearBaseCells)
+	linearBaseCells = 8
+	defer func(cells int) { linearMemoryCells = cells }(linearMemoryCells)
+
+	r := rand.New(rand.NewSource(8))
+	opts := []AlignOptions{
+		{Mode: Global},
+		{Mode: Local},
+		{Mode: SemiGlobal},
+		{Mode: SemiGlobal, Ends: ThreePrimeEnds},
+		{Mode: SemiGlobal, Ends: FivePrimeEnds},
+		{Mode: Global, Scoring: UnitScoring, Ties: TieBreak{GapsFirst: true}},
+		{Mode: Local, Scoring: NUC44Scoring},
+		{Mode: SemiGlobal, Ends: FreeEnds{QueryStart: true, SubjectEnd: true}, Scoring: Scoring{Match: 2, Mismatch: -3, GapOpen: 5, GapExtend: 2}},
+	}
+
+	failures := 0
+	for n := 0; n < 2000; n++ {
+		subject := randomSequence(r, 10+r.Intn(40))
+		query := mutateSequence(r, subject, 0.3)
+		if n%2 == 0 {
+			query = randomSequence(r, 10+r.Intn(40))
+		}
+		o := opts[n%len(opts)]
+
+		// the full matrices, the linear memory algorithm and a band
+		linearMemoryCells = 1 << 22
+		full := query.Align(subject, o)
+		linearMemoryCells = 1
+		linear := query.Align(subject, o)
+		banded := query.align(subject, o.Mode, o.ends(), o.scoring(), o.Ties, &Band{Width: 4, Offset: r.Intn(9) - 4})
+
+		for k, a := range []PairWiseAlignment{full, linear, banded} {
+			if !reachable(a.Score) {
+				continue
+			}
+			if score := cigarScore(a, o.Mode, o.ends(), o.scoring()); score != a.Score && failures < 10 {
+				failures++
+				t.Errorf("test %d (%d): %+v, %s to %s: expected %s to score %d, but it scores %d",
+					n, k, o, string(query), string(subject), a.ExpandedCIGAR, a.Score, score)
+			}
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package gobioinfo
+
+// Aligner aligns one query to many subjects with the same AlignOptions, such as a linker
+// to each read of a run. The scores of the query bases are worked out once, and the
+// alignment matrices are kept from one alignment to the next, so that they are only
+// allocated again for a subject longer than any before it.
+//
+// An Aligner must not be used by more than one goroutine at a time; give each goroutine
+// its own.
+type Aligner struct {
+	a           *aligner
+	bothStrands bool
+}
+
+// NewAligner returns an Aligner for the query with the mode, free ends and scoring of an
+// AlignOptions, or the defaults of Align if none is given
+func NewAligner(query NucleotideSequence, opts ...AlignOptions) *Aligner {
+	o := firstOptions(opts)
+	a := newAligner(query, nil, o.Mode, o.ends(), o.scoring(), o.Ties, nil)
+	a.precompute()
+	return &Aligner{a: a, bothStrands: o.BothStrands}
+}
+
+// Align aligns the query to the subject, giving the same alignment as
+// query.Align(subject, opts...)
+func (al *Aligner) Align(subject NucleotideSequence) PairWiseAlignment {
+	return onBothStrands(subject, al.bothStrands, func(s NucleotideSequence) PairWiseAlignment {
+		al.a.reset(s)
+		return al.a.align()
+	})
+}
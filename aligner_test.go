@@ -0,0 +1,100 @@
+package gobioinfo
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// func (al *Aligner) Align(subject NucleotideSequence) PairWiseAlignment
+func TestAligner(t *testing.T) {
+	fmt.Println("testing Aligner.Align()...")
+
+	r := rand.New(rand.NewSource(7))
+
+	optionsList := []AlignOptions{
+		{},
+		{Ends: ThreePrimeEnds},
+		{Ends: FivePrimeEnds, Scoring: NUC44Scoring},
+		{Mode: Global, Scoring: UnitScoring},
+		{Mode: Local},
+		{Ends: ThreePrimeEnds, BothStrands: true},
+	}
+
+	for _, opts := range optionsList {
+		query := randomSequence(r, 20+r.Intn(30))
+		aligner := NewAligner(query, opts)
+
+		// subjects of all lengths, so that the buffers are reused both larger and smaller
+		for n := 0; n < 100; n++ {
+			subject := mutateSequence(r, append(randomSequence(r, r.Intn(40)), query...), 0.2)
+			subject = append(subject, randomSequence(r, r.Intn(40))...)
+			if n%10 == 0 {
+				subject = randomSequence(r, r.Intn(5))
+			}
+
+			expected := query.Align(subject, opts)
+			result := aligner.Align(subject)
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("%+v: aligning %s to %s, expected %+v, but got %+v",
+					opts, string(query), string(subject), expected, result)
+			}
+		}
+	}
+
+	// long subjects are aligned in linear memory, in the same way
+	defer func(cells int) { linearMemoryCells = cells }(linearMemoryCells)
+	linearMemoryCells = 100
+
+	query := randomSequence(r, 30)
+	aligner := NewAligner(query, AlignOptions{Ends: ThreePrimeEnds})
+	for n := 0; n < 20; n++ {
+		subject := mutateSequence(r, append(randomSequence(r, r.Intn(40)), query...), 0.2)
+		expected := query.Align(subject, AlignOptions{Ends: ThreePrimeEnds})
+		if result := aligner.Align(subject); !reflect.DeepEqual(result, expected) {
+			t.Errorf("aligning %s to %s in linear memory, expected %+v, but got %+v",
+				string(query), string(subject), expected, result)
+		}
+	}
+}
+
+func TestAlignerAllocations(t *testing.T) {
+	fmt.Println("testing Aligner.Align() allocations...")
+
+	r := rand.New(rand.NewSource(15))
+	query := randomSequence(r, 40)
+	subject := mutateSequence(r, append(randomSequence(r, 60), query...), 0.2)
+
+	// once its buffers have grown, an Aligner only allocates the strings of each
+	// alignment, which share one allocation
+	for _, opts := range []AlignOptions{{Ends: ThreePrimeEnds}, {Mode: Global}, {Mode: Local, Scoring: NUC44Scoring}} {
+		aligner := NewAligner(query, opts)
+		aligner.Align(subject)
+		if allocs := testing.AllocsPerRun(100, func() { aligner.Align(subject) }); allocs > 1 {
+			t.Errorf("%+v: expected at most 1 allocation per alignment, but got %v", opts, allocs)
+		}
+	}
+}
+
+func BenchmarkAligner(b *testing.B) {
+	reads := benchmarkReads(b)
+	aligner := NewAligner(benchmarkAdapter.Sequence, AlignOptions{Ends: FivePrimeEnds})
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, read := range reads {
+			aligner.Align(read.Sequence)
+		}
+	}
+}
+
+func BenchmarkAlignerOneShot(b *testing.B) {
+	reads := benchmarkReads(b)
+	opts := AlignOptions{Ends: FivePrimeEnds}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, read := range reads {
+			benchmarkAdapter.Sequence.Align(read.Sequence, opts)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package gobioinfo
+
+// Band restricts an alignment to the diagonals of the alignment matrix near where the
+// alignment is expected, such as when realigning reads to a known amplicon or merging
+// overlapping mates. A diagonal is the position in the subject minus the position in the
+// query, so a query expected to start 10 bases into the subject lies on diagonal 10.
+type Band struct {
+	Width  int // number of diagonals either side of Offset which are filled
+	Offset int // the expected diagonal
+}
+
+// outside reports whether the cell at subject position i and query position j of the
+// alignment matrix lies outside of the band
+func (b *Band) outside(i int, j int) bool {
+	diagonal := i - j - b.Offset
+	return diagonal < -b.Width || diagonal > b.Width
+}
+
+// onEdge reports whether the alignment reaches the outermost diagonals of the band (or
+// goes beyond them), in which case a better alignment may lie outside of the band
+func (b *Band) onEdge(a PairWiseAlignment) bool {
+	diagonal := a.SubjectStart - a.QueryStart - b.Offset
+	if diagonal <= -b.Width || diagonal >= b.Width {
+		return true
+	}
+	for k := 0; k < len(a.ExpandedCIGAR); k++ {
+		switch a.ExpandedCIGAR[k] {
+		case 'i':
+			diagonal++
+		case 'j':
+			diagonal--
+		default:
+			continue
+		}
+		if diagonal <= -b.Width || diagonal >= b.Width {
+			return true
+		}
+	}
+	return false
+}
+
+// BandedAlign aligns the query to the subject as Align does, but only fills the cells of
+// the alignment matrices within the band, and only keeps those for the traceback, which
+// is much faster when the band is narrow and takes memory in proportion to its width.
+// If the alignment reaches the edge of the band, the query is aligned again using the
+// full matrices and onEdge is true. Only the alignment found within the band is checked,
+// so, as with other banded aligners, a better alignment which would have to leave the band
+// is occasionally missed; the band should be centred on where the alignment is expected
+// and be wider than the indels expected along it.
+func (q NucleotideSequence) BandedAlign(s NucleotideSequence, band Band, opts ...AlignOptions) (alignment PairWiseAlignment, onEdge bool) {
+
+	o := firstOptions(opts)
+	alignment = q.align(s, o.Mode, o.ends(), o.scoring(), o.Ties, &band)
+	if !band.onEdge(alignment) {
+		return alignment, false
+	}
+
+	return q.Align(s, o), true
+}
@@ -0,0 +1,88 @@
+package gobioinfo
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// func (q NucleotideSequence) BandedAlign(s NucleotideSequence, band Band, opts ...AlignOptions) (alignment PairWiseAlignment, onEdge bool)
+func TestBandedAlign(t *testing.T) {
+	fmt.Println("testing BandedAlign()...")
+
+	amplicon := NucleotideSequence("TCGTCGGCAGCGTCAGATGTGTATAAGAGACAGCCTACGGGNGGCWGCAGTGGGGAATATTGCACAATGG")
+
+	type testPair struct {
+		query        string
+		band         Band
+		opts         AlignOptions
+		onEdge       bool
+		subjectStart int
+		queryStart   int
+	}
+
+	testSuite := []testPair{
+		// a read of the amplicon with a few substitutions
+		{"TCGTCGGCAGCGTCAGATGTGTATAAGAGACAGCCTACGGGAGGCAGCAGTGGGGAATATTGCACAATGG", Band{Width: 4}, AlignOptions{Mode: Global}, false, 0, 0},
+		// a 1 base deletion stays inside the band
+		{"TCGTCGGCAGCGTCAGATGTGTATAAGAGACAGCTACGGGAGGCAGCAGTGGGGAATATTGCACAATGG", Band{Width: 4}, AlignOptions{Mode: Global}, false, 0, 0},
+		// an 8 base deletion leaves it, so the full matrices are used
+		{"TCGTCGGCAGCGTCAGATGTGTATAAGACGGGAGGCAGCAGTGGGGAATATTGCACAATGG", Band{Width: 4}, AlignOptions{Mode: Global}, true, 0, 0},
+		// the end of the amplicon, found along diagonal 42
+		{"GGCAGCAGTGGGGAATATTGCACAATGG", Band{Width: 4, Offset: 40}, AlignOptions{}, false, 42, 0},
+		// the same read, with the band expected too far to one side
+		{"GGCAGCAGTGGGGAATATTGCACAATGG", Band{Width: 2, Offset: 40}, AlignOptions{}, true, 42, 0},
+	}
+
+	for i, elem := range testSuite {
+		query := NucleotideSequence(elem.query)
+		result, onEdge := query.BandedAlign(amplicon, elem.band, elem.opts)
+		full := query.Align(amplicon, elem.opts)
+		if onEdge != elem.onEdge {
+			t.Errorf("test %d: expected onEdge to be %v, but got %v", i, elem.onEdge, onEdge)
+		}
+		if result.SubjectStart != elem.subjectStart || result.QueryStart != elem.queryStart {
+			t.Errorf("test %d: expected the alignment to start at s%d q%d, but got s%d q%d",
+				i, elem.subjectStart, elem.queryStart, result.SubjectStart, result.QueryStart)
+		}
+		if result.ExpandedCIGAR != full.ExpandedCIGAR {
+			t.Errorf("test %d: expected the banded alignment %q to match the full alignment %q",
+				i, result.ExpandedCIGAR, full.ExpandedCIGAR)
+		}
+	}
+}
+
+func TestBandedAlignMemory(t *testing.T) {
+	fmt.Println("testing that BandedAlign() memory grows with the band width...")
+
+	r := rand.New(rand.NewSource(11))
+	subject := randomSequence(r, 1000)
+	query := mutateSequence(r, subject, 0.02)
+
+	// the movements kept for the traceback take the band's cells of each row, not the
+	// whole row, and a local alignment only looks for its end among them
+	for _, mode := range []AlignMode{Global, Local} {
+		full := query.Align(subject, AlignOptions{Mode: mode})
+		for _, width := range []int{8, 16, 64, 256} {
+			a := newAligner(query, subject, mode, FreeEnds{}, DefaultScoring, TieBreak{}, &Band{Width: width})
+			alignment := a.align()
+			if cells, limit := cap(a.D), (2*width+1)*(len(query)+1); cells > limit {
+				t.Errorf("%v, width %d: expected at most %d cells of movements, but got %d", mode, width, limit, cells)
+			}
+			if !reflect.DeepEqual(alignment, full) {
+				t.Errorf("%v, width %d: expected the banded alignment to be the full alignment %s, but got %s", mode, width, full.ExpandedCIGAR, alignment.ExpandedCIGAR)
+			}
+		}
+	}
+}
+
+func BenchmarkBandedLocalAlign(b *testing.B) {
+	r := rand.New(rand.NewSource(11))
+	subject := randomSequence(r, 10000)
+	query := mutateSequence(r, subject, 0.02)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		query.BandedAlign(subject, Band{Width: 16}, AlignOptions{Mode: Local})
+	}
+}
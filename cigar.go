@@ -0,0 +1,296 @@
+package gobioinfo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+/*
+SAM compatible descriptions of a PairWiseAlignment.
+
+The Subject is treated as the reference sequence and the Query as the read, so the
+columns of the ExpandedCIGAR translate to SAM operations as:
+
+	m  match                       M or =
+	x  mismatch                    M or X
+	n  neutral (N or ambiguity)    M, and = if the two bases are identical or X if not
+	i  subject base, query gap     D
+	j  query base, subject gap     I
+
+and any part of the query outside of the alignment is soft clipped (S).
+*/
+
+// CIGAROp is a single operation of a SAM CIGAR string, such as the "5M" in "3S5M1I2M"
+type CIGAROp struct {
+	Op  byte // one of MIDNSHP=X
+	Len int
+}
+
+func (o CIGAROp) String() string {
+	return strconv.Itoa(o.Len) + string(o.Op)
+}
+
+// ParseCIGAR splits a SAM CIGAR string into its operations
+func ParseCIGAR(cigar string) ([]CIGAROp, error) {
+	var ops []CIGAROp
+	length := 0
+	hasLength := false
+	for i := 0; i < len(cigar); i++ {
+		c := cigar[i]
+		switch {
+		case c >= '0' && c <= '9':
+			length = length*10 + int(c-'0')
+			hasLength = true
+		case strings.IndexByte("MIDNSHP=X", c) >= 0:
+			if !hasLength || length == 0 {
+				return nil, fmt.Errorf("CIGAR %q: operation %q at position %d has no length", cigar, c, i)
+			}
+			ops = append(ops, CIGAROp{Op: c, Len: length})
+			length = 0
+			hasLength = false
+		default:
+			return nil, fmt.Errorf("CIGAR %q: invalid character %q at position %d", cigar, c, i)
+		}
+	}
+	if hasLength {
+		return nil, fmt.Errorf("CIGAR %q: trailing length without an operation", cigar)
+	}
+	return ops, nil
+}
+
+// identicalBases reports whether two bases are the same letter, ignoring case
+func identicalBases(a rune, b rune) bool {
+	return unicode.ToUpper(a) == unicode.ToUpper(b)
+}
+
+// compactCIGAR run-length encodes the ExpandedCIGAR, translating each column with op and
+// adding soft clips for the unaligned ends of the query
+func (a PairWiseAlignment) compactCIGAR(op func(column byte, subjectBase rune, queryBase rune) byte) string {
+	if a.ExpandedCIGAR == "" {
+		return "*"
+	}
+
+	var ops []CIGAROp
+	add := func(o byte, n int) {
+		if n == 0 {
+			return
+		}
+		if len(ops) > 0 && ops[len(ops)-1].Op == o {
+			ops[len(ops)-1].Len += n
+			return
+		}
+		ops = append(ops, CIGAROp{Op: o, Len: n})
+	}
+
+	add('S', a.QueryStart)
+
+	subjectPosition, queryPosition := a.SubjectStart, a.QueryStart
+	for i := 0; i < len(a.ExpandedCIGAR); i++ {
+		var subjectBase, queryBase rune
+		column := a.ExpandedCIGAR[i]
+		if column != 'j' {
+			subjectBase = a.Subject[subjectPosition]
+			subjectPosition++
+		}
+		if column != 'i' {
+			queryBase = a.Query[queryPosition]
+			queryPosition++
+		}
+		add(op(column, subjectBase, queryBase), 1)
+	}
+
+	add('S', len(a.Query)-queryPosition)
+
+	var cigar strings.Builder
+	for _, o := range ops {
+		cigar.WriteString(o.String())
+	}
+	return cigar.String()
+}
+
+// CIGAR returns the SAM CIGAR string of the alignment using M for both matches and
+// mismatches, or "*" if there is no alignment
+func (a PairWiseAlignment) CIGAR() string {
+	return a.compactCIGAR(func(column byte, subjectBase rune, queryBase rune) byte {
+		switch column {
+		case 'i':
+			return 'D'
+		case 'j':
+			return 'I'
+		}
+		return 'M'
+	})
+}
+
+// ExtendedCIGAR returns the SAM CIGAR string of the alignment using = for identical bases
+// and X for any others, or "*" if there is no alignment
+func (a PairWiseAlignment) ExtendedCIGAR() string {
+	return a.compactCIGAR(func(column byte, subjectBase rune, queryBase rune) byte {
+		switch {
+		case column == 'i':
+			return 'D'
+		case column == 'j':
+			return 'I'
+		case column == 'm' || identicalBases(subjectBase, queryBase):
+			return '='
+		}
+		return 'X'
+	})
+}
+
+// MD returns the SAM MD tag value of the alignment (without the "MD:Z:" prefix), which
+// spells out the subject bases at mismatches and deletions so that the subject can be
+// rebuilt from the query and CIGAR
+func (a PairWiseAlignment) MD() string {
+	if a.ExpandedCIGAR == "" {
+		return ""
+	}
+
+	var md strings.Builder
+	matches := 0
+	deleting := false
+
+	subjectPosition, queryPosition := a.SubjectStart, a.QueryStart
+	for i := 0; i < len(a.ExpandedCIGAR); i++ {
+		column := a.ExpandedCIGAR[i]
+		switch column {
+		case 'i':
+			if !deleting {
+				md.WriteString(strconv.Itoa(matches))
+				md.WriteByte('^')
+				matches = 0
+				deleting = true
+			}
+			md.WriteRune(unicode.ToUpper(a.Subject[subjectPosition]))
+			subjectPosition++
+			continue
+		case 'j':
+			// insertions are not part of the MD tag
+			queryPosition++
+			continue
+		}
+
+		deleting = false
+		subjectBase := a.Subject[subjectPosition]
+		if column == 'm' || identicalBases(subjectBase, a.Query[queryPosition]) {
+			matches++
+		} else {
+			md.WriteString(strconv.Itoa(matches))
+			md.WriteRune(unicode.ToUpper(subjectBase))
+			matches = 0
+		}
+		subjectPosition++
+		queryPosition++
+	}
+	md.WriteString(strconv.Itoa(matches))
+
+	return md.String()
+}
+
+// NM returns the edit distance between the aligned parts of the query and subject: the
+// number of mismatched (including N and ambiguous) positions plus inserted and deleted bases
+func (a PairWiseAlignment) NM() int {
+	distance := 0
+	subjectPosition, queryPosition := a.SubjectStart, a.QueryStart
+	for i := 0; i < len(a.ExpandedCIGAR); i++ {
+		switch a.ExpandedCIGAR[i] {
+		case 'i':
+			distance++
+			subjectPosition++
+		case 'j':
+			distance++
+			queryPosition++
+		default:
+			if !identicalBases(a.Subject[subjectPosition], a.Query[queryPosition]) {
+				distance++
+			}
+			subjectPosition++
+			queryPosition++
+		}
+	}
+	return distance
+}
+
+// ApplyCIGAR returns a copy of the alignment with its Subject and Query realigned as
+// described by a SAM CIGAR string, starting at the 0-based subjectStart. Soft clips set
+// the QueryStart, hard clips and padding are ignored, and skipped regions (N) are treated
+// as deletions.
+func (a PairWiseAlignment) ApplyCIGAR(subjectStart int, cigar string) (PairWiseAlignment, error) {
+
+	parsed, err := ParseCIGAR(cigar)
+	if err != nil {
+		return a, err
+	}
+	if subjectStart < 0 || subjectStart > len(a.Subject) {
+		return a, fmt.Errorf("CIGAR start %d is outside of a subject of length %d", subjectStart, len(a.Subject))
+	}
+
+	// hard clips and padding do not involve the stored sequences
+	var ops []CIGAROp
+	for _, o := range parsed {
+		if o.Op != 'H' && o.Op != 'P' {
+			ops = append(ops, o)
+		}
+	}
+
+	queryStart := 0
+	var expanded strings.Builder
+	subjectPosition := subjectStart
+	queryPosition := 0
+
+	for k, o := range ops {
+		switch o.Op {
+		case 'S':
+			if k != 0 && k != len(ops)-1 {
+				return a, fmt.Errorf("CIGAR %q: soft clip in the middle of the alignment", cigar)
+			}
+			if k == 0 {
+				queryStart = o.Len
+			}
+			queryPosition += o.Len
+			continue
+		}
+
+		for n := 0; n < o.Len; n++ {
+			switch o.Op {
+			case 'D', 'N':
+				expanded.WriteByte('i')
+				subjectPosition++
+			case 'I':
+				expanded.WriteByte('j')
+				queryPosition++
+			default:
+				if subjectPosition >= len(a.Subject) || queryPosition >= len(a.Query) {
+					return a, fmt.Errorf("CIGAR %q runs past the end of the sequences", cigar)
+				}
+				switch classifyPair(a.Subject[subjectPosition], a.Query[queryPosition]) {
+				case match:
+					expanded.WriteByte('m')
+				case neutral:
+					expanded.WriteByte('n')
+				default:
+					expanded.WriteByte('x')
+				}
+				subjectPosition++
+				queryPosition++
+			}
+		}
+	}
+
+	if subjectPosition > len(a.Subject) || queryPosition != len(a.Query) {
+		return a, fmt.Errorf("CIGAR %q does not fit a query of length %d and subject of length %d from %d",
+			cigar, len(a.Query), len(a.Subject), subjectStart)
+	}
+
+	newAlignment := PairWiseAlignment{
+		Subject:       a.Subject,
+		Query:         a.Query,
+		ExpandedCIGAR: expanded.String(),
+		SubjectStart:  subjectStart,
+		QueryStart:    queryStart,
+	}
+
+	return alignmentRepr(newAlignment), nil
+}
@@ -0,0 +1,92 @@
+package gobioinfo
+
+import (
+	"fmt"
+	"testing"
+)
+
+// func ParseCIGAR(cigar string) ([]CIGAROp, error) {}
+func TestParseCIGAR(t *testing.T) {
+	fmt.Println("testing ParseCIGAR()...")
+
+	ops, err := ParseCIGAR("5H58S4M1D36=1X2S")
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	expected := []CIGAROp{{'H', 5}, {'S', 58}, {'M', 4}, {'D', 1}, {'=', 36}, {'X', 1}, {'S', 2}}
+	if len(ops) != len(expected) {
+		t.Fatal("expected ", expected, " but got ", ops)
+	}
+	for i := range ops {
+		if ops[i] != expected[i] {
+			t.Error("expected ", expected[i], " but got ", ops[i])
+		}
+	}
+
+	for _, bad := range []string{"M", "5", "5M3", "0M", "5Q", "5M-1D"} {
+		if _, err := ParseCIGAR(bad); err == nil {
+			t.Errorf("expected an error parsing %q", bad)
+		}
+	}
+}
+
+// func (a PairWiseAlignment) CIGAR() string {}
+func TestPairWiseAlignmentSAM(t *testing.T) {
+	fmt.Println("testing PairWiseAlignment CIGAR, MD and NM...")
+
+	read := NucleotideSequence("GCTAGGGAGGACGATGCGGTGGTGATGCTGCCACATACACTAAGAAGGTCCTGGACGCGTGTAGTCACTTCCAGCGGTCGTATGCCGTCTTCTGCTTGAA")
+
+	type testGroup struct {
+		alignment     PairWiseAlignment
+		cigar         string
+		extendedCIGAR string
+		md            string
+		nm            int
+	}
+
+	testSuite := []testGroup{
+		{NucleotideSequence("GGACGTACGT").GlobalAlign(NucleotideSequence("ACGTACGTCC"), AlignOptions{}),
+			"2I8M2D", "2I8=2D", "8^CC0", 4},
+		{read.SG3pAlign(NucleotideSequence("GTGTCAGTCACTTCCAGCGGTCGTATGCCGTCTTCTGCTTG")),
+			"58S4M1D36M2S", "58S4=1D36=2S", "4^C36", 1},
+		{read.SG3pAlign(NucleotideSequence("GTGTCAGTCACTTNCAGCGGACGTATGCCGTCTTCTGCTTG")),
+			"58S4M1D36M2S", "58S4=1D8=1X6=1X20=2S", "4^C8N6A20", 3},
+		{NucleotideSequence("AGCAGGGAGG").SG3pAlign(NucleotideSequence("TTTTTTTT")),
+			"*", "*", "", 0},
+	}
+
+	for _, elem := range testSuite {
+		a := elem.alignment
+		if a.CIGAR() != elem.cigar || a.ExtendedCIGAR() != elem.extendedCIGAR || a.MD() != elem.md || a.NM() != elem.nm {
+			t.Errorf("expected %s %s %s %d, but got %s %s %s %d", elem.cigar, elem.extendedCIGAR, elem.md, elem.nm,
+				a.CIGAR(), a.ExtendedCIGAR(), a.MD(), a.NM())
+		}
+		if a.ExpandedCIGAR == "" {
+			continue
+		}
+
+		// both flavours of CIGAR parse back into the same alignment
+		for _, cigar := range []string{a.CIGAR(), a.ExtendedCIGAR()} {
+			b, err := PairWiseAlignment{Subject: a.Subject, Query: a.Query}.ApplyCIGAR(a.SubjectStart, cigar)
+			if err != nil {
+				t.Error("unexpected error from ApplyCIGAR: ", err)
+				continue
+			}
+			if b.ExpandedCIGAR != a.ExpandedCIGAR || b.QueryStart != a.QueryStart ||
+				b.GappedQuery != a.GappedQuery || b.GappedSubject != a.GappedSubject {
+				t.Errorf("%s did not parse back into %s from %d, got %s from %d",
+					cigar, a.ExpandedCIGAR, a.QueryStart, b.ExpandedCIGAR, b.QueryStart)
+			}
+		}
+	}
+
+	a := PairWiseAlignment{Subject: NucleotideSequence("ACGT"), Query: NucleotideSequence("ACGT")}
+	for _, bad := range []string{"5M", "2M1S1M", "3M", "2M3D2M"} {
+		if _, err := a.ApplyCIGAR(0, bad); err == nil {
+			t.Errorf("expected an error applying %q", bad)
+		}
+	}
+	if _, err := a.ApplyCIGAR(-1, "4M"); err == nil {
+		t.Error("expected an error applying a CIGAR before the start of the subject")
+	}
+}
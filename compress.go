@@ -0,0 +1,176 @@
+package gobioinfo
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+/*
+Transparent handling of compressed sequence files.
+
+OpenReader sniffs the first few bytes of its input and wraps it in the matching
+decompressor, so that the same code can scan plain and compressed files:
+
+	file, _ := os.Open("sample.fastq.gz")
+	r, err := OpenReader(file)
+	...
+	scanner := NewFASTQScanner(r)
+
+gzip (including multi-member files such as BGZF) and bzip2 input are supported out of
+the box via the standard library. zstd is not: this package has no dependencies outside
+the standard library, which has no zstd codec, so zstd input is only detected, and
+OpenReader returns ErrUnsupportedCompression for it until a decompressor is registered
+with RegisterDecompressor (and a compressor with RegisterCompressor for writing), eg.
+wrapping github.com/klauspost/compress/zstd:
+
+	gobioinfo.RegisterDecompressor(gobioinfo.Zstd, func(r io.Reader) (io.ReadCloser, error) {
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	})
+*/
+
+// Compression identifies the compression format of a stream
+type Compression int
+
+// Compression formats that can be detected by DetectCompression
+const (
+	Uncompressed Compression = iota
+	Gzip
+	Bzip2
+	// Zstd is detected, but can only be read or written once a codec is registered
+	Zstd
+)
+
+func (c Compression) String() string {
+	switch c {
+	case Uncompressed:
+		return "uncompressed"
+	case Gzip:
+		return "gzip"
+	case Bzip2:
+		return "bzip2"
+	case Zstd:
+		return "zstd"
+	}
+	return fmt.Sprintf("Compression(%d)", int(c))
+}
+
+// ErrUnsupportedCompression is returned when there is no registered codec for a
+// compression format
+var ErrUnsupportedCompression = errors.New("no codec registered for compression format")
+
+// magic numbers at the start of each compressed format
+var compressionMagic = []struct {
+	compression Compression
+	magic       []byte
+}{
+	{Gzip, []byte{0x1f, 0x8b}},
+	{Bzip2, []byte{'B', 'Z', 'h'}},
+	{Zstd, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+}
+
+var decompressors = map[Compression]func(io.Reader) (io.ReadCloser, error){
+	// gzip.Reader reads multi-member streams by default, which covers BGZF
+	Gzip: func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	Bzip2: func(r io.Reader) (io.ReadCloser, error) {
+		return ioutil.NopCloser(bzip2.NewReader(r)), nil
+	},
+}
+
+var compressors = map[Compression]func(io.Writer) (io.WriteCloser, error){
+	Gzip: func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriter(w), nil
+	},
+}
+
+// RegisterDecompressor sets the function used to decompress input of the given
+// format, replacing any existing one. It is not safe to call concurrently with OpenReader.
+func RegisterDecompressor(c Compression, fn func(io.Reader) (io.ReadCloser, error)) {
+	decompressors[c] = fn
+}
+
+// RegisterCompressor sets the function used to compress output in the given format,
+// replacing any existing one. It is not safe to call concurrently with NewFASTQWriterCompressed.
+func RegisterCompressor(c Compression, fn func(io.Writer) (io.WriteCloser, error)) {
+	compressors[c] = fn
+}
+
+// DetectCompression peeks at the start of r, without consuming anything, and returns the
+// compression format it finds there. Input too short to hold a magic number is treated
+// as uncompressed.
+func DetectCompression(r *bufio.Reader) (Compression, error) {
+	header, err := r.Peek(4)
+	if err != nil && err != io.EOF {
+		return Uncompressed, err
+	}
+	for _, elem := range compressionMagic {
+		if bytes.HasPrefix(header, elem.magic) {
+			return elem.compression, nil
+		}
+	}
+	return Uncompressed, nil
+}
+
+// OpenReader detects the compression format of r and returns a reader of the
+// decompressed data, suitable to be handed to NewFASTQScanner or NewFASTAScanner. Closing
+// the returned reader releases the decompressor but does not close r. Input in a format
+// with no registered decompressor, such as zstd by default, gives an error wrapping
+// ErrUnsupportedCompression.
+func OpenReader(r io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+
+	c, err := DetectCompression(buffered)
+	if err != nil {
+		return nil, err
+	}
+	if c == Uncompressed {
+		return ioutil.NopCloser(buffered), nil
+	}
+
+	decompress, ok := decompressors[c]
+	if !ok {
+		return nil, fmt.Errorf("%v input: %w", c, ErrUnsupportedCompression)
+	}
+	return decompress(buffered)
+}
+
+// compressedFile closes both the decompressor and the file underneath it
+type compressedFile struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (f compressedFile) Close() error {
+	err := f.ReadCloser.Close()
+	if fileErr := f.file.Close(); err == nil {
+		err = fileErr
+	}
+	return err
+}
+
+// OpenFile opens the file at filePath and passes it through OpenReader. Closing the
+// returned reader also closes the file.
+func OpenFile(filePath string) (io.ReadCloser, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	r, err := OpenReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return compressedFile{ReadCloser: r, file: file}, nil
+}
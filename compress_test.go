@@ -0,0 +1,125 @@
+package gobioinfo
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+// this is the single read "@r1\nACGTN\n+\nIIII#\n" compressed with bzip2
+var bzip2RawData = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0xd1, 0xc3, 0xef, 0xc4, 0x00, 0x00,
+	0x04, 0x5e, 0x80, 0x40, 0x10, 0x08, 0x08, 0x20, 0x00, 0x68, 0xa1, 0x04, 0x00, 0x10, 0x00, 0x20,
+	0x00, 0x22, 0x09, 0x8d, 0x47, 0xa4, 0xf4, 0x20, 0x1a, 0x00, 0x39, 0x40, 0x58, 0x50, 0xc7, 0x0b,
+	0xca, 0x4b, 0x45, 0xdc, 0x91, 0x4e, 0x14, 0x24, 0x34, 0x70, 0xfb, 0xf1, 0x00,
+}
+
+// func OpenReader(r io.Reader) (io.ReadCloser, error) {}
+func TestOpenReader(t *testing.T) {
+	fmt.Println("testing OpenReader()...")
+
+	// a multi-member gzip stream, as produced by BGZF or by concatenating .gz files
+	var multiMember bytes.Buffer
+	for _, member := range []string{"@r1\nACGT\n+\nIIII\n", "@r2\nTTTT\n+\nJJJJ\n"} {
+		w := gzip.NewWriter(&multiMember)
+		w.Write([]byte(member))
+		w.Close()
+	}
+
+	type testPair struct {
+		input  []byte
+		output string
+	}
+
+	testSuite := []testPair{
+		{gzipRawData, rawUnzipped},
+		{multiMember.Bytes(), "@r1\nACGT\n+\nIIII\n@r2\nTTTT\n+\nJJJJ\n"},
+		{bzip2RawData, "@r1\nACGTN\n+\nIIII#\n"},
+		{[]byte(rawUnzipped), rawUnzipped},
+		{[]byte("@"), "@"},
+		{[]byte{}, ""},
+	}
+
+	for _, elem := range testSuite {
+		r, err := OpenReader(bytes.NewReader(elem.input))
+		if err != nil {
+			t.Error("unexpected error from OpenReader: ", err)
+			continue
+		}
+		result, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Error("unexpected error reading decompressed data: ", err)
+		}
+		if string(result) != elem.output {
+			t.Errorf("expected %q, but got %q", elem.output, string(result))
+		}
+	}
+
+	zstdData := []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00}
+
+	if _, err := OpenReader(bytes.NewReader(zstdData)); !errors.Is(err, ErrUnsupportedCompression) {
+		t.Error("expected ErrUnsupportedCompression for zstd input, but got ", err)
+	}
+
+	RegisterDecompressor(Zstd, func(r io.Reader) (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewBufferString("decompressed")), nil
+	})
+	defer delete(decompressors, Zstd)
+
+	r, err := OpenReader(bytes.NewReader(zstdData))
+	if err != nil {
+		t.Fatal("unexpected error after registering a zstd decompressor: ", err)
+	}
+	if result, _ := ioutil.ReadAll(r); string(result) != "decompressed" {
+		t.Error("registered zstd decompressor was not used, got ", string(result))
+	}
+}
+
+// func NewFASTQWriterCompressed(w io.Writer, c Compression) (FASTQWriter, error) {}
+func TestNewFASTQWriterCompressed(t *testing.T) {
+	fmt.Println("testing NewFASTQWriterCompressed()...")
+
+	var compressed bytes.Buffer
+
+	w, err := NewFASTQWriterCompressed(&compressed, Gzip)
+	if err != nil {
+		t.Fatal("unexpected error creating a gzip FASTQWriter: ", err)
+	}
+
+	scanner := NewFASTQScanner(bytes.NewBufferString(rawUnzipped))
+	for {
+		read, err := scanner.NextRead()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal("unexpected error reading test data: ", err)
+		}
+		w.Write(read)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal("unexpected error closing the FASTQWriter: ", err)
+	}
+
+	if c, _ := DetectCompression(bufio.NewReader(bytes.NewReader(compressed.Bytes()))); c != Gzip {
+		t.Error("expected gzip output, but detected ", c)
+	}
+
+	r, err := OpenReader(&compressed)
+	if err != nil {
+		t.Fatal("unexpected error from OpenReader: ", err)
+	}
+	result, _ := ioutil.ReadAll(r)
+	if string(result) != rawUnzipped {
+		t.Error("compressed FASTQ output did not round trip")
+	}
+
+	if _, err := NewFASTQWriterCompressed(&compressed, Bzip2); !errors.Is(err, ErrUnsupportedCompression) {
+		t.Error("expected ErrUnsupportedCompression for bzip2 output, but got ", err)
+	}
+}
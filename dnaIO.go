@@ -13,51 +13,287 @@ import (
 // read in a FASTQ file in a iterative manner via the Next() function
 type FASTQScanner struct {
 	*bufio.Scanner
+	// Encoding is the name of the quality encoding used to decode reads (a key of
+	// PHREDEncodings). It defaults to phred+33 and can be inferred with DetectEncoding.
+	Encoding string
+	record   int // number of records started so far
+	line     int // number of lines scanned so far
+	// sampled holds records read ahead by DetectEncoding, which are returned by NextRead
+	// before scanning continues, followed by sampleErr if sampling stopped on an error
+	sampled   []fastqRecord
+	sampleErr error
+}
+
+// fastqRecord holds the raw lines of a FASTQ record and where it was found
+type fastqRecord struct {
+	ln1, ln3 string
+	ln2, ln4 []rune
+	record   int
+	line     int // line number of the quality line
 }
 
 // NewFASTQScanner takes an io.Reader and returns a FASTQScanner
 func NewFASTQScanner(r io.Reader) FASTQScanner {
-	return FASTQScanner{Scanner: bufio.NewScanner(r)}
+	return FASTQScanner{Scanner: bufio.NewScanner(r), Encoding: EncodingPHRED33}
+}
+
+// Reasons a FASTQ record can fail to parse, carried in the Err field of a ParseError
+var (
+	ErrTruncatedRecord  = errors.New("truncated record")
+	ErrMissingHeader    = errors.New("header line does not start with '@'")
+	ErrMissingSeparator = errors.New("separator line does not start with '+'")
+	ErrLengthMismatch   = errors.New("sequence and quality lengths differ")
+)
+
+// ParseError is returned by FASTQScanner.NextRead when a record is malformed or the
+// underlying bufio.Scanner fails
+type ParseError struct {
+	Record int   // 1-based number of the record being parsed
+	Line   int   // 1-based line number at which the problem was found
+	Err    error // the reason, one of the Err* values above or a bufio.Scanner error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("fastq: record %d, line %d: %v", e.Record, e.Line, e.Err)
+}
+
+// Unwrap returns the reason the record could not be parsed
+func (e *ParseError) Unwrap() error {
+	return e.Err
 }
 
-// NextRead returns the next read from a FASTQScanner
+// scanLine advances to the next line of the current record, returning a ParseError if
+// the input ends (or the Scanner fails) before it can
+func (s *FASTQScanner) scanLine() (string, error) {
+	if !s.Scanner.Scan() {
+		err := s.Scanner.Err()
+		if err == nil {
+			err = ErrTruncatedRecord
+		}
+		return "", &ParseError{Record: s.record, Line: s.line + 1, Err: err}
+	}
+	s.line++
+	return s.Scanner.Text(), nil
+}
+
+// NextRead returns the next read from a FASTQScanner, or io.EOF once the input ends
+// cleanly between records. Malformed or truncated records, including quality characters
+// outside the range of the Encoding, are reported as a *ParseError.
 func (s *FASTQScanner) NextRead() (FASTQRead, error) {
 
-	var ln1, ln3 string
-	var ln2, ln4 []rune
-	var newRead FASTQRead
+	var rec fastqRecord
+	var err error
 
-	if s.Scanner.Scan() {
-		ln1 = s.Scanner.Text()
-	} else {
-		return FASTQRead{}, errors.New("EOF")
+	switch {
+	case len(s.sampled) > 0:
+		rec = s.sampled[0]
+		s.sampled = s.sampled[1:]
+	case s.sampleErr != nil:
+		err, s.sampleErr = s.sampleErr, nil
+		return FASTQRead{}, err
+	default:
+		rec, err = s.nextRecord()
+		if err != nil {
+			return FASTQRead{}, err
+		}
 	}
-	if s.Scanner.Scan() {
-		ln2 = []rune(s.Scanner.Text())
-	} else {
-		return FASTQRead{}, errors.New("EOF")
+
+	newRead, err := NewFASTQReadEncoded(rec.ln1, rec.ln2, rec.ln3, rec.ln4, s.Encoding)
+	if err != nil {
+		return FASTQRead{}, &ParseError{Record: rec.record, Line: rec.line, Err: err}
 	}
-	if s.Scanner.Scan() {
-		ln3 = s.Scanner.Text()
-	} else {
-		return FASTQRead{}, errors.New("EOF")
+
+	return newRead, nil
+
+}
+
+// nextRecord scans the four lines of the next record and checks its structure
+func (s *FASTQScanner) nextRecord() (fastqRecord, error) {
+
+	var rec fastqRecord
+
+	// find the header line, skipping any blank lines between records
+	for rec.ln1 == "" {
+		if !s.Scanner.Scan() {
+			if err := s.Scanner.Err(); err != nil {
+				return rec, &ParseError{Record: s.record + 1, Line: s.line + 1, Err: err}
+			}
+			return rec, io.EOF
+		}
+		s.line++
+		rec.ln1 = s.Scanner.Text()
 	}
-	if s.Scanner.Scan() {
-		ln4 = []rune(s.Scanner.Text())
-	} else {
-		return FASTQRead{}, errors.New("EOF")
+	s.record++
+	if rec.ln1[0] != '@' {
+		return rec, &ParseError{Record: s.record, Line: s.line, Err: ErrMissingHeader}
+	}
+
+	line, err := s.scanLine()
+	if err != nil {
+		return rec, err
+	}
+	rec.ln2 = []rune(line)
+
+	rec.ln3, err = s.scanLine()
+	if err != nil {
+		return rec, err
+	}
+	if len(rec.ln3) == 0 || rec.ln3[0] != '+' {
+		return rec, &ParseError{Record: s.record, Line: s.line, Err: ErrMissingSeparator}
+	}
+
+	line, err = s.scanLine()
+	if err != nil {
+		return rec, err
+	}
+	rec.ln4 = []rune(line)
+	if len(rec.ln4) != len(rec.ln2) {
+		return rec, &ParseError{Record: s.record, Line: s.line, Err: ErrLengthMismatch}
 	}
 
-	newRead = NewFASTQRead(ln1, ln2, ln3, ln4)
+	rec.record = s.record
+	rec.line = s.line
+
+	return rec, nil
+}
+
+// DetectEncoding reads ahead up to n records, infers their quality encoding with
+// InferEncoding and sets it as the scanner's Encoding. The sampled records are not lost:
+// they are returned by the following calls to NextRead. Input with no quality characters
+// in the sample is left as phred+33, as is input whose encoding is ambiguous, which is
+// reported with an error wrapping ErrAmbiguousEncoding. If sampling stops on a malformed
+// record the encoding is still inferred from the records before it, and the error is
+// returned both here and, in sequence, by NextRead.
+func (s *FASTQScanner) DetectEncoding(n int) (string, error) {
+
+	lowest, highest := rune(-1), rune(-1)
+
+	for i := len(s.sampled); i < n && s.sampleErr == nil; i++ {
+		rec, err := s.nextRecord()
+		if err != nil {
+			// keep the error to be returned by NextRead in sequence
+			s.sampleErr = err
+			break
+		}
+		s.sampled = append(s.sampled, rec)
+	}
+
+	for _, rec := range s.sampled {
+		for _, char := range rec.ln4 {
+			if lowest == -1 || char < lowest {
+				lowest = char
+			}
+			if char > highest {
+				highest = char
+			}
+		}
+	}
+
+	s.Encoding = EncodingPHRED33
+	var inferErr error
+	if lowest != -1 {
+		var encoding string
+		if encoding, inferErr = InferEncoding(lowest, highest); inferErr == nil {
+			s.Encoding = encoding
+		}
+	}
+
+	if s.sampleErr != nil && s.sampleErr != io.EOF {
+		return s.Encoding, s.sampleErr
+	}
+	return s.Encoding, inferErr
+}
+
+// FASTAScanner is a wrapper around bufio.Scanner, which allows for easily accessing each
+// record in a FASTA file in a iterative manner via the NextRead() function. Sequences may
+// be split over any number of lines, and blank lines are ignored.
+type FASTAScanner struct {
+	*bufio.Scanner
+	// header holds the header line of the next record, which has already been
+	// consumed from the Scanner while reading the end of the previous record
+	header    string
+	hasHeader bool
+}
+
+// NewFASTAScanner takes an io.Reader and returns a FASTAScanner
+func NewFASTAScanner(r io.Reader) FASTAScanner {
+	return FASTAScanner{Scanner: bufio.NewScanner(r)}
+}
+
+// NextRead returns the next record from a FASTAScanner, or io.EOF once there are
+// no more records
+func (s *FASTAScanner) NextRead() (FASTARead, error) {
+
+	// find the header of this record, skipping any leading blank lines
+	for !s.hasHeader {
+		if !s.Scanner.Scan() {
+			if err := s.Scanner.Err(); err != nil {
+				return FASTARead{}, err
+			}
+			return FASTARead{}, io.EOF
+		}
+		line := strings.TrimSpace(s.Scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case line[0] == '>':
+			s.header = line
+			s.hasHeader = true
+		default:
+			return FASTARead{}, fmt.Errorf("FASTA sequence data found before a '>' header: %q", line)
+		}
+	}
+
+	id, description := splitFASTAHeader(s.header)
+	s.hasHeader = false
+
+	// collect sequence lines until the next header or the end of the input
+	var sequence NucleotideSequence
+	for s.Scanner.Scan() {
+		line := strings.TrimSpace(s.Scanner.Text())
+		if line == "" {
+			continue
+		}
+		if line[0] == '>' {
+			s.header = line
+			s.hasHeader = true
+			break
+		}
+		sequence = append(sequence, []rune(line)...)
+	}
+	if err := s.Scanner.Err(); err != nil {
+		return FASTARead{}, err
+	}
+
+	newRead := FASTARead{
+		ID:          id,
+		Description: description,
+		DNASequence: DNASequence{
+			Sequence: sequence,
+		},
+	}
 
 	return newRead, nil
+}
 
+// splitFASTAHeader splits a ">"-prefixed header line into the ID (the first word) and
+// the description (everything after the first run of whitespace)
+func splitFASTAHeader(header string) (id string, description string) {
+	header = strings.TrimPrefix(header, ">")
+	split := strings.IndexAny(header, " \t")
+	if split < 0 {
+		return header, ""
+	}
+	return header[:split], strings.TrimSpace(header[split+1:])
 }
 
 // FASTQWriter defines the FASTQWriter structure, which contains a pointer to the file to
 // which FASTQReads will be written and to a bufio.Writer instance
 type FASTQWriter struct {
 	*bufio.Writer
+	// compressor is the compressing writer underneath the bufio.Writer, if any, and
+	// must be closed to flush its trailer
+	compressor io.WriteCloser
 }
 
 // NewFASTQWriter takes an io.Writer and returns a FASTQWriter
@@ -65,6 +301,24 @@ func NewFASTQWriter(w io.Writer) FASTQWriter {
 	return FASTQWriter{Writer: bufio.NewWriter(w)}
 }
 
+// NewFASTQWriterCompressed takes an io.Writer and returns a FASTQWriter whose output is
+// compressed in the given format. The FASTQWriter must be closed to complete the
+// compressed stream; this does not close w.
+func NewFASTQWriterCompressed(w io.Writer, c Compression) (FASTQWriter, error) {
+	if c == Uncompressed {
+		return NewFASTQWriter(w), nil
+	}
+	compress, ok := compressors[c]
+	if !ok {
+		return FASTQWriter{}, fmt.Errorf("%v output: %w", c, ErrUnsupportedCompression)
+	}
+	compressor, err := compress(w)
+	if err != nil {
+		return FASTQWriter{}, err
+	}
+	return FASTQWriter{Writer: bufio.NewWriter(compressor), compressor: compressor}, nil
+}
+
 //writes a FASTQRead to file, returns an error
 func (w *FASTQWriter) Write(r FASTQRead) error {
 
@@ -92,10 +346,16 @@ func (w *FASTQWriter) Write(r FASTQRead) error {
 	return (err)
 }
 
-// Close flushes the FASTQWriter buffer and closes it
-func (w *FASTQWriter) Close() {
-	w.Writer.Flush()
-	fmt.Println("closing the writer")
+// Close flushes the FASTQWriter buffer and closes it, finishing the compressed stream
+// if the FASTQWriter compresses its output
+func (w *FASTQWriter) Close() error {
+	err := w.Writer.Flush()
+	if w.compressor != nil {
+		if closeErr := w.compressor.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
 }
 
 /*
@@ -119,7 +379,8 @@ func NewFASTAWriterPath(filePath string) FASTAWriter {
 	file, err := os.Create(filePath)
 
 	if err != nil {
-		fmt.Println("error opening file= ", err)
+		// report the error on stderr, so that it does not end up in output written to stdout
+		fmt.Fprintln(os.Stderr, "error opening file= ", err)
 		os.Exit(1)
 	}
 	fastawriter := FASTAWriter{Writer: bufio.NewWriter(file), File: file}
@@ -151,7 +412,6 @@ func (w *FASTAWriter) Write(r FASTQRead) error {
 func (w *FASTAWriter) Close() {
 	w.Writer.Flush()
 	w.File.Close()
-	fmt.Println("closing FASTA writer")
 }
 
 /*
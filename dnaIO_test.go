@@ -1,12 +1,14 @@
 package gobioinfo
 
 import (
-	// "bufio"
-	// "bytes"
+	"bufio"
+	"bytes"
 	// "compress/gzip"
-	// "fmt"
+	"fmt"
+	"io"
 	// "os"
-	// "testing"
+	"strings"
+	"testing"
 )
 
 // func NewFASTQScanner(filePath string) FASTQScanner
@@ -14,10 +16,59 @@ import (
 //
 // }
 
-//func (s *FASTQScanner) NextRead() FASTQRead {}
-// func TestFASTQScannerNextRead(t *testing.T) {
-//
-// }
+// func (s *FASTQScanner) NextRead() (FASTQRead, error) {}
+func TestFASTQScannerNextRead(t *testing.T) {
+	fmt.Println("testing FASTQScanner.NextRead()...")
+
+	scanner := NewFASTQScanner(bytes.NewBufferString(rawUnzipped + "\n"))
+
+	count := 0
+	for {
+		_, err := scanner.NextRead()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		count++
+	}
+	if count != 10 {
+		t.Error("expected 10 reads, but got ", count)
+	}
+
+	type testPair struct {
+		input  string
+		record int
+		line   int
+		reason error
+	}
+
+	testSuite := []testPair{
+		{"@r1\nACGT\n+\nIIII\n@r2\nACGT\n+\n", 2, 8, ErrTruncatedRecord},
+		{"@r1\nACGT\n", 1, 3, ErrTruncatedRecord},
+		{"@r1\nACGT\n+\nIIII\nr2\nACGT\n+\nIIII\n", 2, 5, ErrMissingHeader},
+		{"@r1\nACGT\n-\nIIII\n", 1, 3, ErrMissingSeparator},
+		{"@r1\nACGT\n+\nIII\n", 1, 4, ErrLengthMismatch},
+		{"@r1\n" + strings.Repeat("A", bufio.MaxScanTokenSize+1) + "\n+\n", 1, 2, bufio.ErrTooLong},
+	}
+
+	for _, elem := range testSuite {
+		scanner := NewFASTQScanner(bytes.NewBufferString(elem.input))
+		var err error
+		for err == nil {
+			_, err = scanner.NextRead()
+		}
+		parseErr, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("expected a *ParseError for %q, but got %v", elem.input, err)
+			continue
+		}
+		if parseErr.Record != elem.record || parseErr.Line != elem.line || parseErr.Err != elem.reason {
+			t.Errorf("expected record %d, line %d, reason %v, but got %v",
+				elem.record, elem.line, elem.reason, parseErr)
+		}
+	}
+}
 
 // func NewFASTQWriter(filePath string) FASTQWriter {}
 
@@ -88,6 +139,49 @@ import (
 // 	w.Close()
 // }
 
+// func (s *FASTAScanner) NextRead() (FASTARead, error) {}
+func TestFASTAScannerNextRead(t *testing.T) {
+	fmt.Println("testing FASTAScanner.NextRead()...")
+
+	rawTestData := bytes.NewBufferString("\r\n>linker 3' linker\tsequence\r\n" +
+		"GTGTCAGTCACTTCCAG\r\nCGGTCGTATGCCGTCTTCTGCTTG\r\n\r\n" +
+		">empty\n" +
+		">adapter\n" +
+		"AGATCGGAAG\n" +
+		"AGC")
+
+	expected := []FASTARead{
+		{ID: "linker", Description: "3' linker\tsequence",
+			DNASequence: NewDNASequence("GTGTCAGTCACTTCCAGCGGTCGTATGCCGTCTTCTGCTTG")},
+		{ID: "empty"},
+		{ID: "adapter", DNASequence: NewDNASequence("AGATCGGAAGAGC")},
+	}
+
+	scanner := NewFASTAScanner(rawTestData)
+
+	for _, elem := range expected {
+		read, err := scanner.NextRead()
+		if err != nil {
+			t.Fatal("unexpected error from NextRead: ", err)
+		}
+		if read.ID != elem.ID || read.Description != elem.Description {
+			t.Errorf("expected header %q %q, but got %q %q", elem.ID, elem.Description, read.ID, read.Description)
+		}
+		if string(read.Sequence) != string(elem.Sequence) {
+			t.Errorf("expected sequence %q, but got %q", string(elem.Sequence), string(read.Sequence))
+		}
+	}
+
+	if _, err := scanner.NextRead(); err != io.EOF {
+		t.Error("expected io.EOF at the end of the input, but got ", err)
+	}
+
+	badScanner := NewFASTAScanner(bytes.NewBufferString("ACGT\n>read\nACGT\n"))
+	if _, err := badScanner.NextRead(); err == nil || err == io.EOF {
+		t.Error("expected an error for sequence data before the first header, but got ", err)
+	}
+}
+
 // func NewFASTAWriter(filePath string) FASTAWriter {}
 // func (w *FASTAWriter) Write(r FASTQRead) error {}
 // func (w *FASTAWriter) Close() {}
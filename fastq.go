@@ -1,6 +1,10 @@
 package gobioinfo
 
-//	"fmt"
+import (
+	"errors"
+	"fmt"
+	"math"
+)
 
 // FASTQRead is structure holding all of the elements of a FASTQ read, which includes a sequences,
 // a quality (PHRED) sequence, an ID sequence, and a miscallaneous string (line 3 of each FASTQRead).
@@ -25,16 +29,73 @@ type PHRED struct {
 	Encoding string
 }
 
-// NewFASTQRead ...
+// Names of the supported quality encodings, which are the keys of PHREDEncodings
+const (
+	EncodingPHRED33 = "phred+33" // Sanger, Illumina 1.8+, PacBio and Nanopore
+	EncodingPHRED64 = "phred+64" // Illumina 1.3 to 1.7
+	EncodingSolexa  = "solexa"   // Solexa and Illumina before 1.3, log-odds scores
+)
+
+// QualityEncoding describes how quality scores are encoded as ASCII characters: each
+// score is the character's code point minus the Offset.
+type QualityEncoding struct {
+	Offset rune // the character encoding a score of 0
+	Min    rune // the lowest valid character
+	Max    rune // the highest valid character
+	// Solexa scores are log-odds rather than PHRED scores, and are converted to PHRED
+	// scores when decoded
+	Solexa bool
+}
+
+// PHREDEncodings holds the supported quality encodings by name. "illumina_1.8" is kept as
+// an alias of "phred+33".
+var PHREDEncodings = map[string]QualityEncoding{
+	EncodingPHRED33: {Offset: '!', Min: '!', Max: '~'},
+	"illumina_1.8":  {Offset: '!', Min: '!', Max: '~'},
+	EncodingPHRED64: {Offset: '@', Min: '@', Max: '~'},
+	EncodingSolexa:  {Offset: '@', Min: ';', Max: '~', Solexa: true},
+}
+
+// QualityError is returned when a quality character is outside of the range allowed by
+// its encoding
+type QualityError struct {
+	Position int    // 0-based position of the character in the quality string
+	Char     rune   // the offending character
+	Encoding string // name of the encoding used
+}
+
+func (e *QualityError) Error() string {
+	return fmt.Sprintf("quality character %q at position %d is out of range for %s", e.Char, e.Position, e.Encoding)
+}
+
+// solexaToPHRED converts Solexa scores, offset by 5 so that the lowest score of -5 is at
+// index 0, to the equivalent PHRED scores: Q = 10*log10(10^(Qsolexa/10) + 1)
+var solexaToPHRED = func() (table [68]uint8) {
+	for i := range table {
+		solexa := float64(i - 5)
+		table[i] = uint8(math.Floor(10*math.Log10(math.Pow(10, solexa/10)+1) + 0.5))
+	}
+	return table
+}()
+
+// NewFASTQRead creates a FASTQRead from the four lines of a FASTQ record, decoding the
+// quality line as phred+33. Quality characters out of that range decode to 0; use
+// NewFASTQReadEncoded to check them.
 func NewFASTQRead(ln1 string, ln2 []rune, ln3 string, ln4 []rune) (newRead FASTQRead) {
+	newRead, _ = NewFASTQReadEncoded(ln1, ln2, ln3, ln4, EncodingPHRED33)
+	return (newRead)
+}
 
-	phredEncoding := "illumina_1.8"
+// NewFASTQReadEncoded creates a FASTQRead from the four lines of a FASTQ record, decoding
+// the quality line with the named encoding. The read is returned even if some quality
+// characters are invalid, along with the *QualityError for the first of them.
+func NewFASTQReadEncoded(ln1 string, ln2 []rune, ln3 string, ln4 []rune, phredEncoding string) (FASTQRead, error) {
 
-	decodedQuality := DecodePHRED(ln4, phredEncoding)
+	decodedQuality, err := DecodePHRED(ln4, phredEncoding)
 
 	newSequence := NucleotideSequence(ln2)
 
-	newRead = FASTQRead{
+	newRead := FASTQRead{
 		ID: ln1,
 		DNASequence: DNASequence{
 			Sequence: newSequence,
@@ -47,83 +108,104 @@ func NewFASTQRead(ln1 string, ln2 []rune, ln3 string, ln4 []rune) (newRead FASTQ
 		},
 	}
 
-	return (newRead)
+	return newRead, err
 }
 
-// DecodePHRED takes a
-func DecodePHRED(encoded []rune, encoding string) (decoded []uint8) {
+// ReverseComplement returns the read with its sequence reverse complemented and its
+// quality scores reversed to match, as the read would be on the other strand. The read
+// itself is left as it is.
+func (r FASTQRead) ReverseComplement() FASTQRead {
+
+	reversed := r
+	reversed.Sequence = r.Sequence.ReverseComplement()
+	reversed.Encoded = make([]rune, len(r.Encoded))
+	for k, c := range r.Encoded {
+		reversed.Encoded[len(r.Encoded)-1-k] = c
+	}
+	if r.Decoded != nil {
+		reversed.Decoded = make([]uint8, len(r.Decoded))
+		for k, q := range r.Decoded {
+			reversed.Decoded[len(r.Decoded)-1-k] = q
+		}
+	}
+
+	return reversed
+}
+
+// sub returns the bases of the read from start to end, with their qualities. The new read
+// shares its sequence and qualities with r.
+func (r FASTQRead) sub(start int, end int) FASTQRead {
+	if len(r.Encoded) == len(r.Sequence) {
+		r.Encoded = r.Encoded[start:end]
+	}
+	if len(r.Decoded) == len(r.Sequence) {
+		r.Decoded = r.Decoded[start:end]
+	}
+	r.Sequence = r.Sequence[start:end]
+	return r
+}
+
+// DecodePHRED decodes a quality string with the named encoding into PHRED scores. Invalid
+// characters decode to 0, and the first one is reported as a *QualityError.
+func DecodePHRED(encoded []rune, encoding string) (decoded []uint8, err error) {
+	enc, ok := PHREDEncodings[encoding]
+	if !ok {
+		return make([]uint8, len(encoded)), fmt.Errorf("unrecognized quality encoding %q", encoding)
+	}
+
 	decoded = make([]uint8, len(encoded))
-	for i, rune := range encoded {
-		decoded[i] = PHREDEncodings[encoding][string(rune)]
+	for i, char := range encoded {
+		switch {
+		case char < enc.Min || char > enc.Max:
+			if err == nil {
+				err = &QualityError{Position: i, Char: char, Encoding: encoding}
+			}
+		case enc.Solexa:
+			decoded[i] = solexaToPHRED[char-enc.Offset+5]
+		default:
+			decoded[i] = uint8(char - enc.Offset)
+		}
 	}
-	return decoded
+	return decoded, err
 }
 
 // Decode turns the Encoded part of a PHRED struct and in-place decodes it
 // and stores in the the Decoded element of the PHRED
-func (p *PHRED) Decode() {
-	decodedPHRED := make([]uint8, len(p.Encoded))
-
-	for i, rune := range p.Encoded {
-		decodedPHRED[i] = PHREDEncodings[p.Encoding][string(rune)]
-	}
+func (p *PHRED) Decode() error {
+	decodedPHRED, err := DecodePHRED(p.Encoded, p.Encoding)
 
 	p.Decoded = decodedPHRED
+
+	return err
 }
 
-//TODO:optimization - this encoding and decoding of PHRED scores might be able to be done faster if
-// done with math on the ACII codepoints instead of a map lookup
-//		eg: rune("!") = 33
-//		    rune("\"") = 34
-//			decodedPHRED = rune(base) - 33
-
-// this would also make it easier to add new encodings
-// TODO: rename to phred+33
-
-// PHREDEncodings
-var PHREDEncodings = map[string]map[string]uint8{
-	"illumina_1.8": {
-		"!":  0,
-		"\"": 1,
-		"#":  2,
-		"$":  3,
-		"%":  4,
-		"&":  5,
-		"'":  6,
-		"(":  7,
-		")":  8,
-		"*":  9,
-		"+":  10,
-		",":  11,
-		"-":  12,
-		".":  13,
-		"/":  14,
-		"0":  15,
-		"1":  16,
-		"2":  17,
-		"3":  18,
-		"4":  19,
-		"5":  20,
-		"6":  21,
-		"7":  22,
-		"8":  23,
-		"9":  24,
-		":":  25,
-		";":  26,
-		"<":  27,
-		"=":  28,
-		">":  29,
-		"?":  30,
-		"@":  31,
-		"A":  32,
-		"B":  33,
-		"C":  34,
-		"D":  35,
-		"E":  36,
-		"F":  37,
-		"G":  38,
-		"H":  39,
-		"I":  40,
-		"J":  41,
-	},
+// ErrAmbiguousEncoding is returned by InferEncoding when the qualities could be read in
+// more than one encoding
+var ErrAmbiguousEncoding = errors.New("ambiguous quality encoding")
+
+// InferEncoding guesses the quality encoding from the lowest and highest quality
+// characters seen in a sample of reads:
+//
+//	lowest below ';', or highest 'J' or below       phred+33
+//	lowest '@' or above, highest 'K' to 'j'         phred+64
+//	lowest from ';' up to '?', highest 'K' to 'j'   solexa
+//	lowest ';' or above, highest above 'j'          ErrAmbiguousEncoding
+//
+// Only phred+33 uses the characters below ';', and phred+33 qualities from Illumina
+// instruments stop at 'J' (Q41), so a binned NovaSeq sample of only ':' and 'F' is still
+// phred+33. Above 'J' the sample must be in one of the older encodings, which top out at
+// 'h' to 'j' (Q40 to Q42) but often stay well below 'h' in older, lower quality runs. A
+// sample going beyond 'j' without any low qualities, such as high quality PacBio HiFi
+// reads, can not be told apart from those encodings with less than Q100, and is reported
+// as ambiguous rather than guessed; the encoding then has to be set by the caller.
+func InferEncoding(lowest rune, highest rune) (string, error) {
+	switch {
+	case lowest < ';' || highest <= 'J':
+		return EncodingPHRED33, nil
+	case highest > 'j':
+		return "", fmt.Errorf("%w: qualities from %q to %q", ErrAmbiguousEncoding, lowest, highest)
+	case lowest >= '@':
+		return EncodingPHRED64, nil
+	}
+	return EncodingSolexa, nil
 }
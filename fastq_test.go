@@ -1,9 +1,11 @@
 package gobioinfo
 
 import (
-	// "fmt"
-	//"os/user"
-	// "testing"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
 )
 
 // NewFASTQRead(ln1 string, ln2 []rune, ln3 string, ln4 []rune) (newRead FASTQRead)
@@ -11,29 +13,201 @@ import (
 // 	fmt.Println("testing NewFASTQRead")
 // }
 
-//func DecodePHRED(encoded []rune, encoding string) (decoded []uint8)
-// func TestDecodePHRED(t *testing.T) {
-// 	fmt.Println("testing DecodePHRED")
-// }
+// func DecodePHRED(encoded []rune, encoding string) (decoded []uint8, err error)
+func TestDecodePHRED(t *testing.T) {
+	fmt.Println("testing DecodePHRED()...")
 
-// func (p *PHRED) Decode()
-// func TestDecode(t *testing.T) {
-// 	fmt.Println("testing *PHRED.Decode()")
-// }
+	type testPair struct {
+		input    string
+		encoding string
+		output   []uint8
+	}
+
+	testSuite := []testPair{
+		//                                          @   @   @   F   F   F   F   F   H   H   F   F   F   F   F   H   G   H   J   @   F   H   ?
+		{"@@@FFFFFHHFFFFFHGHJ@FH?", "illumina_1.8", []uint8{31, 31, 31, 37, 37, 37, 37, 37, 39, 39, 37, 37, 37, 37, 37, 39, 38, 39, 41, 31, 37, 39, 30}},
+		{"!+5?IJKL~", EncodingPHRED33, []uint8{0, 10, 20, 30, 40, 41, 42, 43, 93}},
+		{"@JT^hi~", EncodingPHRED64, []uint8{0, 10, 20, 30, 40, 41, 62}},
+		{";@JT~", EncodingSolexa, []uint8{1, 3, 10, 20, 62}},
+	}
+
+	for _, elem := range testSuite {
+		result, err := DecodePHRED([]rune(elem.input), elem.encoding)
+		if err != nil {
+			t.Errorf("unexpected error decoding %q as %s: %v", elem.input, elem.encoding, err)
+		}
+		for i, value := range result {
+			if value != elem.output[i] {
+				t.Error("PHRED decoding not as predicted: got", value, " expeceted ", elem.output[i])
+			}
+		}
+	}
+
+	type errorPair struct {
+		input    string
+		encoding string
+		position int
+	}
+
+	errorSuite := []errorPair{
+		{"II II", EncodingPHRED33, 2},
+		{"hh?h", EncodingPHRED64, 2},
+		{"h:", EncodingSolexa, 1},
+	}
 
-/*
-func TestDecodeQualByteSequence(t *testing.T) {
+	for _, elem := range errorSuite {
+		_, err := DecodePHRED([]rune(elem.input), elem.encoding)
+		var qualErr *QualityError
+		if !errors.As(err, &qualErr) || qualErr.Position != elem.position {
+			t.Errorf("expected a *QualityError at position %d for %q, but got %v", elem.position, elem.input, err)
+		}
+	}
 
-	testSequence := []rune("@@@FFFFFHHFFFFFHGHJ@FH?")
+	if _, err := DecodePHRED([]rune("IIII"), "phred+99"); err == nil {
+		t.Error("expected an error for an unrecognized encoding")
+	}
+}
 
-	result := DecodePHRED(testSequence, "illumina_1.8")
-	//                         @    @   @   F   F   F   F   F   H   H   F   F   F   F   F   H   G   H   J   @   F   H   ?
-	predictedResult := []uint8{31, 31, 31, 37, 37, 37, 37, 37, 39, 39, 37, 37, 37, 37, 37, 39, 38, 39, 41, 31, 37, 39, 30}
+// func InferEncoding(lowest rune, highest rune) (string, error)
+func TestInferEncoding(t *testing.T) {
+	fmt.Println("testing InferEncoding()...")
 
-	for i, value := range result {
-		if value != predictedResult[i] {
-			t.Error("PHRED decoding not as predicted: got", value, " expeceted ", predictedResult[i])
+	type testPair struct {
+		qualities string
+		encoding  string // "" if the encoding is ambiguous
+	}
+
+	testSuite := []testPair{
+		{"#########::FFFFJJJ", EncodingPHRED33},
+		{"!~", EncodingPHRED33},
+		{"@@@@JJJJ", EncodingPHRED33},
+		{"<<<AAJJJ", EncodingPHRED33},
+		// binned NovaSeq qualities
+		{"FFFFF:FF,F:FFFFFFFF#", EncodingPHRED33},
+		{"FFFFFFF:FFFFF:FFFFF", EncodingPHRED33},
+		{"FFFFFFFFFFFFFFFFFFFF", EncodingPHRED33},
+		// PacBio HiFi qualities with some low ones
+		{"~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~0~~~~~~~", EncodingPHRED33},
+		// Illumina 1.3 to 1.7 and Solexa
+		{"hhhhhhhhhhhhhhhhhhfhhhhhhhhhhhhhhhhhhhhhhhhhhhhhhhhBBBBBBBB", EncodingPHRED64},
+		{"hhhhhhhhhhhhhh`hhhhhdhhhhhhh@hhhhhhh", EncodingPHRED64},
+		{"hhhhhhhhhhhhhhhhhhhhhhhhhhhhhhhhhhhh", EncodingPHRED64},
+		{"hhhhhhhhhhhhhhhh\\hhhhhhh;;;;;;;;", EncodingSolexa},
+		{"hhhhhhhhhhhhhhhhhhhhhhhhh???", EncodingSolexa},
+		// older, lower quality phred+64 and Solexa runs which never reach 'h'
+		{"aaaaaa^^^^^^XXXXTTTTOOOOBBBB", EncodingPHRED64},
+		{"@@@@ZZZZ", EncodingPHRED64},
+		{"KKKKKKKK", EncodingPHRED64},
+		{"aaaaaaTTTTOOOO;;;;", EncodingSolexa},
+		// high qualities all along the read, as in PacBio HiFi, could be phred+33, phred+64
+		// or Solexa
+		{"~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~`~~~Z~", ""},
+		{"~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~", ""},
+		{";;;;kkkk", ""},
+	}
+
+	for i, elem := range testSuite {
+		lowest, highest := rune(elem.qualities[0]), rune(elem.qualities[0])
+		for _, q := range elem.qualities {
+			if q < lowest {
+				lowest = q
+			}
+			if q > highest {
+				highest = q
+			}
+		}
+		result, err := InferEncoding(lowest, highest)
+		if elem.encoding == "" {
+			if !errors.Is(err, ErrAmbiguousEncoding) {
+				t.Errorf("test %d: expected %q..%q to be ambiguous, but got %s (%v)", i, lowest, highest, result, err)
+			}
+			continue
+		}
+		if result != elem.encoding || err != nil {
+			t.Errorf("test %d: expected %q..%q to be inferred as %s, but got %s (%v)", i, lowest, highest, elem.encoding, result, err)
 		}
 	}
 }
-*/
+
+// func (s *FASTQScanner) DetectEncoding(n int) (string, error)
+func TestFASTQScannerDetectEncoding(t *testing.T) {
+	fmt.Println("testing FASTQScanner.DetectEncoding()...")
+
+	phred64 := "@r1\nACGTA\n+\nhhhhB\n@r2\nACGTA\n+\nhh@hh\n@r3\nACGTA\n+\nhhhhh\n"
+
+	scanner := NewFASTQScanner(bytes.NewBufferString(phred64))
+
+	encoding, err := scanner.DetectEncoding(2)
+	if err != nil || encoding != EncodingPHRED64 {
+		t.Fatalf("expected phred+64, but got %s (%v)", encoding, err)
+	}
+
+	expected := [][]uint8{
+		{40, 40, 40, 40, 2},
+		{40, 40, 0, 40, 40},
+		{40, 40, 40, 40, 40},
+	}
+
+	for i := 0; ; i++ {
+		read, err := scanner.NextRead()
+		if err == io.EOF {
+			if i != 3 {
+				t.Error("expected 3 reads after detection, but got ", i)
+			}
+			break
+		} else if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		if read.Encoding != EncodingPHRED64 {
+			t.Error("read was not decoded with the detected encoding, got ", read.Encoding)
+		}
+		for j, value := range read.Decoded {
+			if value != expected[i][j] {
+				t.Errorf("read %d: expected qualities %v, but got %v", i, expected[i], read.Decoded)
+				break
+			}
+		}
+	}
+
+	// an ambiguous sample is reported, and left as phred+33
+	scanner = NewFASTQScanner(bytes.NewBufferString("@r1\nACGT\n+\n~~~~\n@r2\nACGT\n+\n~~`~\n"))
+	if encoding, err := scanner.DetectEncoding(10); !errors.Is(err, ErrAmbiguousEncoding) || encoding != EncodingPHRED33 {
+		t.Errorf("expected phred+33 and an ambiguous encoding error, but got %s (%v)", encoding, err)
+	}
+	if read, err := scanner.NextRead(); err != nil || read.Decoded[0] != 93 {
+		t.Errorf("expected the sampled read decoded as phred+33, but got %v (%v)", read.Decoded, err)
+	}
+
+	// a truncated record met while sampling is still returned by NextRead in order
+	scanner = NewFASTQScanner(bytes.NewBufferString("@r1\nACGT\n+\nIIII\n@r2\nACGT\n"))
+	if _, err := scanner.DetectEncoding(10); err == nil {
+		t.Error("expected DetectEncoding to report the truncated record")
+	}
+	if _, err := scanner.NextRead(); err != nil {
+		t.Error("unexpected error for the sampled read: ", err)
+	}
+	if _, err := scanner.NextRead(); !errors.Is(err, ErrTruncatedRecord) {
+		t.Error("expected the truncated record error, but got ", err)
+	}
+}
+
+// func (r FASTQRead) ReverseComplement() FASTQRead
+func TestFASTQReadReverseComplement(t *testing.T) {
+	fmt.Println("testing FASTQRead.ReverseComplement()...")
+
+	read := NewFASTQRead("@read1", []rune("AACGN"), "+", []rune("!#%'I"))
+	reversed := read.ReverseComplement()
+
+	if string(reversed.Sequence) != "NCGTT" || string(reversed.Encoded) != "I'%#!" {
+		t.Errorf("expected NCGTT with I'%%#!, but got %s with %s", string(reversed.Sequence), string(reversed.Encoded))
+	}
+	if expected := []uint8{40, 6, 4, 2, 0}; !bytes.Equal(reversed.Decoded, expected) {
+		t.Errorf("expected the scores %v, but got %v", expected, reversed.Decoded)
+	}
+	if reversed.ID != read.ID || reversed.Misc != read.Misc || reversed.Encoding != read.Encoding {
+		t.Errorf("expected the header lines and encoding to be kept, but got %+v", reversed)
+	}
+	if string(read.Sequence) != "AACGN" || string(read.Encoded) != "!#%'I" || read.Decoded[0] != 0 {
+		t.Errorf("expected the read to be left as it was, but got %+v", read)
+	}
+}
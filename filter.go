@@ -0,0 +1,266 @@
+package gobioinfo
+
+import (
+	"fmt"
+	"math"
+	"unicode"
+)
+
+/*
+Read filtering.
+
+A ReadFilter tests one property of a read, and is named for the reason it rejects reads.
+The filters below cover the usual tests after trimming: length, N content, quality and
+sequence complexity. A Filter runs a list of them over each read, stopping at the first
+which rejects it, and counts the reads each rejects so the totals can be reported at the
+end of a run.
+*/
+
+// ReadFilter decides whether to keep a read. Name is the reason recorded for the reads it
+// rejects.
+type ReadFilter struct {
+	Name string
+	Keep func(r FASTQRead) bool
+}
+
+// MinLengthFilter rejects reads shorter than n bases
+func MinLengthFilter(n int) ReadFilter {
+	return ReadFilter{"min_length", func(r FASTQRead) bool { return len(r.Sequence) >= n }}
+}
+
+// MaxLengthFilter rejects reads longer than n bases
+func MaxLengthFilter(n int) ReadFilter {
+	return ReadFilter{"max_length", func(r FASTQRead) bool { return len(r.Sequence) <= n }}
+}
+
+// MaxNsFilter rejects reads with more than n Ns
+func MaxNsFilter(n int) ReadFilter {
+	return ReadFilter{"max_n", func(r FASTQRead) bool { return r.Sequence.CountNs() <= n }}
+}
+
+// MaxNFractionFilter rejects reads in which more than fraction of the bases are N
+func MaxNFractionFilter(fraction float64) ReadFilter {
+	return ReadFilter{"max_n_fraction", func(r FASTQRead) bool {
+		return ratio(r.Sequence.CountNs(), len(r.Sequence), 1) <= fraction
+	}}
+}
+
+// MinMeanQualityFilter rejects reads whose mean quality is below quality (Trimmomatic
+// AVGQUAL), and reads without decoded qualities
+func MinMeanQualityFilter(quality float64) ReadFilter {
+	return ReadFilter{"min_mean_quality", func(r FASTQRead) bool {
+		return r.hasQualities() && r.MeanQuality() >= quality
+	}}
+}
+
+// MaxExpectedErrorsFilter rejects reads expected to have more than errors errors, as
+// worked out by ExpectedErrors (usearch -fastq_maxee), and reads without decoded
+// qualities
+func MaxExpectedErrorsFilter(errors float64) ReadFilter {
+	return ReadFilter{"max_expected_errors", func(r FASTQRead) bool {
+		return r.hasQualities() && r.ExpectedErrors() <= errors
+	}}
+}
+
+// MaxDustFilter rejects low complexity reads, whose DustScore is above score
+func MaxDustFilter(score float64) ReadFilter {
+	return ReadFilter{"max_dust", func(r FASTQRead) bool { return r.Sequence.DustScore() <= score }}
+}
+
+// MinEntropyFilter rejects low complexity reads, whose Entropy is below entropy bits
+func MinEntropyFilter(entropy float64) ReadFilter {
+	return ReadFilter{"min_entropy", func(r FASTQRead) bool { return r.Sequence.Entropy() >= entropy }}
+}
+
+// MaxHomopolymerFilter rejects reads with a run of more than n of the same base
+func MaxHomopolymerFilter(n int) ReadFilter {
+	return ReadFilter{"max_homopolymer", func(r FASTQRead) bool { return r.Sequence.LongestHomopolymer() <= n }}
+}
+
+// CountNs returns the number of Ns in the sequence
+func (q NucleotideSequence) CountNs() int {
+	n := 0
+	for _, base := range q {
+		if base == 'N' || base == 'n' {
+			n++
+		}
+	}
+	return n
+}
+
+// LongestHomopolymer returns the length of the longest run of the same base in the
+// sequence, ignoring case
+func (q NucleotideSequence) LongestHomopolymer() int {
+	longest, run := 0, 0
+	for k, base := range q {
+		if k > 0 && unicode.ToUpper(base) == unicode.ToUpper(q[k-1]) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
+
+// baseCodes numbers A, C, G and T (in either case) from 0 to 3, and everything else -1
+var baseCodes = func() (codes [128]int8) {
+	for k := range codes {
+		codes[k] = -1
+	}
+	for k, base := range "ACGT" {
+		codes[base], codes[unicode.ToLower(base)] = int8(k), int8(k)
+	}
+	return codes
+}()
+
+// triplets calls visit with the index (0 to 63) of each triplet of A, C, G and T in the
+// sequence; triplets with any other base are skipped
+func (q NucleotideSequence) triplets(visit func(triplet int)) {
+	codes := &baseCodes
+	for k := 0; k+3 <= len(q); k++ {
+		triplet := 0
+		for _, base := range q[k : k+3] {
+			if base < 0 || base >= 128 || codes[base] < 0 {
+				triplet = -1
+				break
+			}
+			triplet = triplet<<2 | int(codes[base])
+		}
+		if triplet >= 0 {
+			visit(triplet)
+		}
+	}
+}
+
+// DustScore returns the DUST score of the sequence as a whole: for each of the 64
+// triplets of bases occurring c times, c(c-1)/2, summed and divided by one less than the
+// number of triplets. Repetitive sequences score highly (a homopolymer of length l scores
+// (l-2)/2) while random sequences score near 0. A score above 7 is commonly taken to be
+// low complexity. Triplets containing an N or any other code are left out.
+func (q NucleotideSequence) DustScore() float64 {
+	var counts [64]int
+	total := 0
+	q.triplets(func(triplet int) {
+		counts[triplet]++
+		total++
+	})
+	if total < 2 {
+		return 0
+	}
+
+	score := 0
+	for _, c := range counts {
+		score += c * (c - 1) / 2
+	}
+	return float64(score) / float64(total-1)
+}
+
+// Entropy returns the Shannon entropy, in bits, of the frequencies of the triplets of
+// bases in the sequence: 0 for a homopolymer, up to 6 for a long random sequence.
+// Triplets containing an N or any other code are left out.
+func (q NucleotideSequence) Entropy() float64 {
+	var counts [64]int
+	total := 0
+	q.triplets(func(triplet int) {
+		counts[triplet]++
+		total++
+	})
+
+	entropy := 0.0
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / float64(total)
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy
+}
+
+// MeanQuality returns the mean of the decoded qualities of the read, or 0 if it has none
+func (r FASTQRead) MeanQuality() float64 {
+	total := 0
+	for _, q := range r.Decoded {
+		total += int(q)
+	}
+	return ratio(total, len(r.Decoded), 1)
+}
+
+// errorProbabilities holds the probability that a base of each PHRED quality is wrong
+var errorProbabilities = func() (table [256]float64) {
+	for q := range table {
+		table[q] = math.Pow(10, -float64(q)/10)
+	}
+	return table
+}()
+
+// ExpectedErrors returns the number of errors expected in the read: the sum of the error
+// probabilities 10^(-Q/10) of its bases
+func (r FASTQRead) ExpectedErrors() float64 {
+	errors := 0.0
+	for _, q := range r.Decoded {
+		errors += errorProbabilities[q]
+	}
+	return errors
+}
+
+// Filter tests reads against a list of ReadFilters, counting the reads each rejects. A
+// Filter must not be used by more than one goroutine at a time.
+type Filter struct {
+	filters  []ReadFilter
+	reads    int
+	rejected []int
+}
+
+// NewFilter returns a Filter applying the filters in the order given
+func NewFilter(filters ...ReadFilter) *Filter {
+	return &Filter{filters: filters, rejected: make([]int, len(filters))}
+}
+
+// Keep reports whether the read passes every filter. A read which fails is counted
+// against the first filter it fails, and is not tested by the rest.
+func (f *Filter) Keep(r FASTQRead) bool {
+	f.reads++
+	for k, filter := range f.filters {
+		if !filter.Keep(r) {
+			f.rejected[k]++
+			return false
+		}
+	}
+	return true
+}
+
+// FilterCount is the number of reads rejected for one reason
+type FilterCount struct {
+	Reason string `json:"reason"`
+	Reads  int    `json:"reads"`
+}
+
+// FilterReport counts the reads tested by a Filter, and why those which failed were
+// rejected
+type FilterReport struct {
+	Reads    int           `json:"reads"`
+	Passed   int           `json:"passed"`
+	Rejected []FilterCount `json:"rejected"` // in the order of the filters
+}
+
+// Report returns the counts of the reads tested so far
+func (f *Filter) Report() FilterReport {
+	report := FilterReport{Reads: f.reads, Passed: f.reads, Rejected: make([]FilterCount, len(f.filters))}
+	for k, filter := range f.filters {
+		report.Rejected[k] = FilterCount{Reason: filter.Name, Reads: f.rejected[k]}
+		report.Passed -= f.rejected[k]
+	}
+	return report
+}
+
+// String returns the report as lines of counts, for printing at the end of a run
+func (r FilterReport) String() string {
+	s := fmt.Sprintf("reads tested: %d\nreads passed: %d (%s)\n", r.Reads, r.Passed, percentage(r.Passed, r.Reads, 1))
+	for _, count := range r.Rejected {
+		s += fmt.Sprintf("rejected by %s: %d (%s)\n", count.Reason, count.Reads, percentage(count.Reads, r.Reads, 1))
+	}
+	return s
+}
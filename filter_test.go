@@ -0,0 +1,113 @@
+package gobioinfo
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestReadFilters(t *testing.T) {
+	fmt.Println("testing the ReadFilters...")
+
+	type testPair struct {
+		filter ReadFilter
+		read   FASTQRead
+		keep   bool
+	}
+
+	testSuite := []testPair{
+		{MinLengthFilter(4), qualityRead("ACGT", 30, 30, 30, 30), true},
+		{MinLengthFilter(5), qualityRead("ACGT", 30, 30, 30, 30), false},
+		{MaxLengthFilter(4), qualityRead("ACGT", 30, 30, 30, 30), true},
+		{MaxLengthFilter(3), qualityRead("ACGT", 30, 30, 30, 30), false},
+		{MaxNsFilter(1), qualityRead("ACNT", 30, 30, 30, 30), true},
+		{MaxNsFilter(1), qualityRead("NCnT", 30, 30, 30, 30), false},
+		{MaxNFractionFilter(0.25), qualityRead("ACNT", 30, 30, 30, 30), true},
+		{MaxNFractionFilter(0.2), qualityRead("ACNT", 30, 30, 30, 30), false},
+		{MinMeanQualityFilter(20), qualityRead("ACGT", 10, 20, 20, 30), true},
+		{MinMeanQualityFilter(20.5), qualityRead("ACGT", 10, 20, 20, 30), false},
+		{MinMeanQualityFilter(0), FASTQRead{DNASequence: DNASequence{Sequence: NucleotideSequence("ACGT")}}, false},
+		// 0.1 + 0.01 + 0.001 + 0.001 expected errors
+		{MaxExpectedErrorsFilter(0.2), qualityRead("ACGT", 10, 20, 30, 30), true},
+		{MaxExpectedErrorsFilter(0.1), qualityRead("ACGT", 10, 20, 30, 30), false},
+		{MaxDustFilter(7), qualityRead("ACGTTGCAAGGCTTACCGATGCA"), true},
+		{MaxDustFilter(7), qualityRead("AAAAAAAAAAAAAAAAAAAAAAAA"), false},
+		{MinEntropyFilter(3), qualityRead("ACGTTGCAAGGCTTACCGATGCA"), true},
+		{MinEntropyFilter(3), qualityRead("CACACACACACACACACACACA"), false},
+		{MaxHomopolymerFilter(4), qualityRead("ACGGGGTA"), true},
+		{MaxHomopolymerFilter(4), qualityRead("ACGGgGGTA"), false},
+	}
+
+	for i, elem := range testSuite {
+		if keep := elem.filter.Keep(elem.read); keep != elem.keep {
+			t.Errorf("test %d: expected %s to keep %s to be %t", i, elem.filter.Name, string(elem.read.Sequence), elem.keep)
+		}
+	}
+}
+
+func TestSequenceComplexity(t *testing.T) {
+	fmt.Println("testing DustScore(), Entropy() and LongestHomopolymer()...")
+
+	type testPair struct {
+		seq         string
+		dust        float64
+		entropy     float64
+		homopolymer int
+	}
+
+	testSuite := []testPair{
+		{"", 0, 0, 0},
+		{"ACG", 0, 0, 1},
+		// a homopolymer of length l scores (l-2)/2
+		{"AAAAAAAAAAAA", 5, 0, 12},
+		// two triplets, each twice
+		{"ACACAC", 2.0 / 3, 1, 1},
+		// five different triplets, with those with an N left out
+		{"ACGTACNNAAC", 0, math.Log2(5), 2},
+	}
+
+	for _, elem := range testSuite {
+		seq := NucleotideSequence(elem.seq)
+		if dust := seq.DustScore(); math.Abs(dust-elem.dust) > 1e-9 {
+			t.Errorf("DustScore() of %s: expected %v, but got %v", elem.seq, elem.dust, dust)
+		}
+		if entropy := seq.Entropy(); math.Abs(entropy-elem.entropy) > 1e-9 {
+			t.Errorf("Entropy() of %s: expected %v, but got %v", elem.seq, elem.entropy, entropy)
+		}
+		if homopolymer := seq.LongestHomopolymer(); homopolymer != elem.homopolymer {
+			t.Errorf("LongestHomopolymer() of %s: expected %d, but got %d", elem.seq, elem.homopolymer, homopolymer)
+		}
+	}
+}
+
+// func (f *Filter) Keep(r FASTQRead) bool
+func TestFilter(t *testing.T) {
+	fmt.Println("testing Filter.Keep() and Filter.Report()...")
+
+	f := NewFilter(MinLengthFilter(4), MaxNsFilter(0), MinMeanQualityFilter(20))
+
+	reads := []FASTQRead{
+		qualityRead("ACGT", 30, 30, 30, 30),
+		qualityRead("ACG", 30, 30, 30),
+		// too short and with an N, but only counted as too short
+		qualityRead("ANG", 30, 30, 30),
+		qualityRead("ACNTA", 30, 30, 30, 30, 30),
+		qualityRead("ACGTA", 10, 10, 10, 10, 10),
+		qualityRead("ACGTAC", 20, 20, 20, 20, 20, 20),
+	}
+	kept := 0
+	for _, read := range reads {
+		if f.Keep(read) {
+			kept++
+		}
+	}
+
+	expected := FilterReport{Reads: 6, Passed: 2, Rejected: []FilterCount{{"min_length", 2}, {"max_n", 1}, {"min_mean_quality", 1}}}
+	if report := f.Report(); !reflect.DeepEqual(report, expected) || kept != 2 {
+		t.Errorf("expected %+v, but got %+v with %d kept", expected, report, kept)
+	}
+	if s := f.Report().String(); s != "reads tested: 6\nreads passed: 2 (33.3%)\nrejected by min_length: 2 (33.3%)\nrejected by max_n: 1 (16.7%)\nrejected by min_mean_quality: 1 (16.7%)\n" {
+		t.Errorf("unexpected report %q", s)
+	}
+}
@@ -0,0 +1,228 @@
+package gobioinfo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*
+Printing alignments.
+
+An alignment is printed as blocks of the query above the subject, wrapped at a fixed
+number of columns, with each line numbered by the 1-based positions of its first and
+last bases. The line between them marks each column of the alignment:
+
+	|  match
+	:  neutral (an N or compatible ambiguity code)
+	.  mismatch
+	   gap
+
+which is the convention of the EMBOSS pair format, where ":" marks similar residues.
+*/
+
+// FormatStyle selects the layout of a formatted alignment
+type FormatStyle int
+
+// Format styles
+const (
+	// BLASTStyle prints a line of statistics and then the blocks, with the subject
+	// labelled "Sbjct", as blastn does
+	BLASTStyle FormatStyle = iota
+	// EMBOSSStyle prints a header of statistics and then the blocks in the "pair" format
+	// of EMBOSS needle and water
+	EMBOSSStyle
+)
+
+// FormatOptions controls how Format lays out an alignment. The zero value gives the
+// layout of String.
+type FormatOptions struct {
+	Style FormatStyle
+	// Width is the number of columns of the alignment in each block, 60 if it is not set
+	Width int
+	// Ruler numbers every tenth column of the alignment, above each block
+	Ruler bool
+	// Color marks mismatches (red), neutral positions (yellow) and gaps (cyan) with ANSI
+	// escape codes, for printing to a terminal
+	Color bool
+	// QueryName and SubjectName label the lines of each block, in place of the defaults
+	// of the Style
+	QueryName   string
+	SubjectName string
+}
+
+// defaultFormatWidth is the number of columns in each block if none is given
+const defaultFormatWidth = 60
+
+// ANSI escape codes for Color
+const (
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+	ansiReset  = "\x1b[0m"
+)
+
+// String returns the alignment formatted in the BLAST style
+func (a PairWiseAlignment) String() string {
+	return a.Format(FormatOptions{})
+}
+
+// Format returns the alignment laid out as set by opts, ending with a newline
+func (a PairWiseAlignment) Format(opts FormatOptions) string {
+
+	width := opts.Width
+	if width <= 0 {
+		width = defaultFormatWidth
+	}
+
+	queryName, subjectName := "Query", "Sbjct"
+	if opts.Style == EMBOSSStyle {
+		queryName, subjectName = "query", "subject"
+	}
+	if opts.QueryName != "" {
+		queryName = opts.QueryName
+	}
+	if opts.SubjectName != "" {
+		subjectName = opts.SubjectName
+	}
+
+	stats := a.Stats()
+	gaps := stats.Length - stats.Matches - stats.Mismatches - stats.Neutral
+
+	var out strings.Builder
+
+	// the label and position widths of each line
+	nameWidth, positionWidth := 0, 0
+	switch opts.Style {
+	case EMBOSSStyle:
+		nameWidth, positionWidth = 13, 6
+		fmt.Fprintf(&out, "#=======================================\n#\n")
+		fmt.Fprintf(&out, "# Aligned_sequences: 2\n# 1: %s\n# 2: %s\n", queryName, subjectName)
+		fmt.Fprintf(&out, "# Length: %d\n", stats.Length)
+		fmt.Fprintf(&out, "# Identity:   %9s (%s)\n", fraction(stats.Matches, stats.Length), percentage(stats.Matches, stats.Length, 1))
+		fmt.Fprintf(&out, "# Similarity: %9s (%s)\n", fraction(stats.Matches+stats.Neutral, stats.Length), percentage(stats.Matches+stats.Neutral, stats.Length, 1))
+		fmt.Fprintf(&out, "# Gaps:       %9s (%s)\n", fraction(gaps, stats.Length), percentage(gaps, stats.Length, 1))
+		fmt.Fprintf(&out, "# Score: %d\n#\n#\n#=======================================\n\n", stats.Score)
+	default:
+		nameWidth = len(queryName)
+		if len(subjectName) > nameWidth {
+			nameWidth = len(subjectName)
+		}
+		positionWidth = len(strconv.Itoa(maxInt([]int{stats.QueryEnd, stats.SubjectEnd})))
+		fmt.Fprintf(&out, " Score = %d, Identities = %s (%s), Gaps = %s (%s)\n\n", stats.Score,
+			fraction(stats.Matches, stats.Length), percentage(stats.Matches, stats.Length, 0),
+			fraction(gaps, stats.Length), percentage(gaps, stats.Length, 0))
+	}
+
+	if a.ExpandedCIGAR == "" {
+		out.WriteString("No alignment\n")
+		return out.String()
+	}
+
+	columns := []rune(a.ExpandedCIGAR)
+	gappedQuery := []rune(a.GappedQuery)
+	gappedSubject := []rune(a.GappedSubject)
+	indent := strings.Repeat(" ", nameWidth+positionWidth+2)
+
+	queryPosition, subjectPosition := a.QueryStart, a.SubjectStart
+	for start := 0; start < len(columns); start += width {
+		end := start + width
+		if end > len(columns) {
+			end = len(columns)
+		}
+		if start > 0 {
+			out.WriteString("\n")
+		}
+
+		// a block too short to reach a fifth column has no ruler
+		if numbers := strings.TrimRight(ruler(start, end), " "); opts.Ruler && numbers != "" {
+			out.WriteString(indent + numbers + "\n")
+		}
+
+		// a line of sequence, numbered by its first and last bases
+		line := func(name string, gapped []rune, position *int) {
+			first := *position + 1
+			var bases strings.Builder
+			for k := start; k < end; k++ {
+				if gapped[k] != '-' {
+					*position++
+				}
+				bases.WriteString(colorColumn(columns[k], string(gapped[k]), opts.Color))
+			}
+			if *position < first {
+				// only gaps, so the numbers are those of the base before them
+				first = *position
+			}
+			fmt.Fprintf(&out, "%-*s %*d %s %d\n", nameWidth, name, positionWidth, first, bases.String(), *position)
+		}
+
+		line(queryName, gappedQuery, &queryPosition)
+
+		var marks strings.Builder
+		for _, column := range columns[start:end] {
+			marks.WriteByte(matchMark(column))
+		}
+		out.WriteString(strings.TrimRight(indent+marks.String(), " ") + "\n")
+
+		line(subjectName, gappedSubject, &subjectPosition)
+	}
+
+	return out.String()
+}
+
+// matchMark returns the character marking a column of the alignment
+func matchMark(column rune) byte {
+	switch column {
+	case 'm':
+		return '|'
+	case 'n':
+		return ':'
+	case 'x':
+		return '.'
+	}
+	return ' '
+}
+
+// colorColumn wraps the text of a column in the ANSI color of its kind, if color is set
+func colorColumn(column rune, text string, color bool) string {
+	if !color {
+		return text
+	}
+	switch column {
+	case 'x':
+		return ansiRed + text + ansiReset
+	case 'n':
+		return ansiYellow + text + ansiReset
+	case 'i', 'j':
+		return ansiCyan + text + ansiReset
+	}
+	return text
+}
+
+// ruler returns a line numbering every tenth column of the alignment from start to end
+// (counting from 0), with a "." every fifth column in between
+func ruler(start int, end int) string {
+	line := []byte(strings.Repeat(" ", end-start))
+	for k := start; k < end; k++ {
+		switch n := k + 1; {
+		case n%10 == 0:
+			number := strconv.Itoa(n)
+			if first := k - start + 1 - len(number); first >= 0 {
+				copy(line[first:], number)
+			}
+		case n%5 == 0:
+			line[k-start] = '.'
+		}
+	}
+	return string(line)
+}
+
+// fraction formats n out of d, as "9/12"
+func fraction(n int, d int) string {
+	return strconv.Itoa(n) + "/" + strconv.Itoa(d)
+}
+
+// percentage formats n out of d as a percentage with the given number of decimals
+func percentage(n int, d int, decimals int) string {
+	return strconv.FormatFloat(ratio(n, d, 100), 'f', decimals, 64) + "%"
+}
@@ -0,0 +1,74 @@
+package gobioinfo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// func (a PairWiseAlignment) Format(opts FormatOptions) string
+func TestFormat(t *testing.T) {
+	fmt.Println("testing PairWiseAlignment.Format()...")
+
+	alignment := NucleotideSequence("GATTNCAGGTCA").Align(NucleotideSequence("CCGATGCAGTCATT"), AlignOptions{})
+
+	testSuite := []struct {
+		alignment PairWiseAlignment
+		opts      FormatOptions
+		expected  string
+	}{
+		{alignment, FormatOptions{}, "" +
+			" Score = 15, Identities = 9/12 (75%), Gaps = 2/12 (17%)\n\n" +
+			"Query  1 GATTNCAGGTCA 12\n" +
+			"         || |:|| ||||\n" +
+			"Sbjct  3 GA-TGCA-GTCA 12\n"},
+		// wrapped with a ruler, and a block with no bases of the subject
+		{alignment, FormatOptions{Width: 5, Ruler: true, QueryName: "read", SubjectName: "ref"}, "" +
+			" Score = 15, Identities = 9/12 (75%), Gaps = 2/12 (17%)\n\n" +
+			"            .\n" +
+			"read  1 GATTN 5\n" +
+			"        || |:\n" +
+			"ref   3 GA-TG 6\n\n" +
+			"           10\n" +
+			"read  6 CAGGT 10\n" +
+			"        || ||\n" +
+			"ref   7 CA-GT 10\n\n" +
+			"read 11 CA 12\n" +
+			"        ||\n" +
+			"ref  11 CA 12\n"},
+		{alignment, FormatOptions{Style: EMBOSSStyle}, "" +
+			"#=======================================\n" +
+			"#\n" +
+			"# Aligned_sequences: 2\n" +
+			"# 1: query\n" +
+			"# 2: subject\n" +
+			"# Length: 12\n" +
+			"# Identity:        9/12 (75.0%)\n" +
+			"# Similarity:     10/12 (83.3%)\n" +
+			"# Gaps:            2/12 (16.7%)\n" +
+			"# Score: 15\n" +
+			"#\n" +
+			"#\n" +
+			"#=======================================\n\n" +
+			"query              1 GATTNCAGGTCA 12\n" +
+			"                     || |:|| ||||\n" +
+			"subject            3 GA-TGCA-GTCA 12\n"},
+		{NucleotideSequence("AAAA").LocalAlign(NucleotideSequence("CCCC"), AlignOptions{}), FormatOptions{},
+			" Score = 0, Identities = 0/0 (0%), Gaps = 0/0 (0%)\n\nNo alignment\n"},
+	}
+
+	for i, elem := range testSuite {
+		if formatted := elem.alignment.Format(elem.opts); formatted != elem.expected {
+			t.Errorf("test %d: expected\n%s\nbut got\n%s", i, elem.expected, formatted)
+		}
+	}
+
+	if alignment.String() != alignment.Format(FormatOptions{}) {
+		t.Errorf("expected String() to format in the BLAST style, but got\n%s", alignment.String())
+	}
+
+	colored := alignment.Format(FormatOptions{Color: true})
+	if !strings.Contains(colored, ansiYellow+"N"+ansiReset) || !strings.Contains(colored, ansiCyan+"-"+ansiReset) {
+		t.Errorf("expected the neutral position and gaps to be colored, but got %q", colored)
+	}
+}
@@ -0,0 +1,391 @@
+package gobioinfo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*
+Illumina read headers.
+
+The ID line of an Illumina read names where on the flowcell the read came from, in one
+of two layouts:
+
+	@HWI-ST560:155:C574EACXX:3:1101:1159:1937 1:N:0:ATCACG     (Casava 1.8 and later)
+	@HWUSI-EAS100R:6:73:941:1973#0/1                          (before Casava 1.8)
+
+Casava 1.8 headers give the instrument, run number, flowcell, lane, tile and x and y
+coordinates of the cluster, optionally followed by a UMI, then after a space the read
+number, whether the read was filtered (Y) or not (N), the control number and the index
+(barcode) sequence or sample number. The older layout gives only the instrument, lane,
+tile and coordinates, with the index after a '#' and the read number after a '/'.
+
+Reads downloaded from the SRA have the run accession and spot number first, followed by
+the original read name if it was kept, and the length of the read:
+
+	@SRR001666.1 071112_SLXA-EAS1_s_7:5:1:817:345 length=36
+*/
+
+// HeaderFormat is the layout of the Illumina part of a read header
+type HeaderFormat int
+
+// Read header formats
+const (
+	// OtherHeader is a header with no Illumina fields, only found in SRA headers which
+	// do not keep an Illumina read name
+	OtherHeader HeaderFormat = iota
+	// CasavaHeader is the layout of Casava 1.8 and later, and of bcl2fastq
+	CasavaHeader
+	// LegacyIlluminaHeader is the layout from before Casava 1.8
+	LegacyIlluminaHeader
+)
+
+// String returns the name of the header format
+func (f HeaderFormat) String() string {
+	switch f {
+	case OtherHeader:
+		return "other"
+	case CasavaHeader:
+		return "casava"
+	case LegacyIlluminaHeader:
+		return "legacy illumina"
+	}
+	return fmt.Sprintf("HeaderFormat(%d)", int(f))
+}
+
+// ReadHeader holds the fields of a read header. Fields which are not part of its Format
+// are left empty (or 0).
+type ReadHeader struct {
+	Format HeaderFormat
+	// Accession is the SRA run accession and spot number (eg. "SRR001666.1"), for reads
+	// from the SRA
+	Accession  string
+	Instrument string
+	Run        int    // Casava only
+	Flowcell   string // Casava only
+	Lane       int
+	Tile       int
+	X          int
+	Y          int
+	UMI        string // Casava only, if the reads were demultiplexed with UMIs
+	Read       int    // the read number (1 or 2), or 0 if it is not given
+	Filtered   bool   // Casava only, whether the read failed the chastity filter
+	Control    int    // Casava only, 0 unless the read is a control
+	// Index is the index sequence (or sample number) of the read, with the two indexes of
+	// dual indexed reads joined by a '+'
+	Index string
+	// Comment is the rest of the header, which is not part of the read name
+	Comment string
+	Length  int // the length of the read given in SRA headers, or 0
+}
+
+// ParseReadHeader parses the ID line of a FASTQ read, with or without its leading "@", or
+// returns an error if it is not an Illumina or SRA header
+func ParseReadHeader(id string) (ReadHeader, error) {
+
+	var h ReadHeader
+	fields := strings.Fields(strings.TrimPrefix(id, "@"))
+	if len(fields) == 0 {
+		return h, fmt.Errorf("empty read header")
+	}
+
+	if isSRAAccession(fields[0]) {
+		h.Accession = fields[0]
+		fields = fields[1:]
+		if last := len(fields) - 1; last >= 0 && strings.HasPrefix(fields[last], "length=") {
+			length, ok := count(strings.TrimPrefix(fields[last], "length="))
+			if !ok {
+				return h, fmt.Errorf("read header %q: invalid %s", id, fields[last])
+			}
+			h.Length = length
+			fields = fields[:last]
+		}
+		if len(fields) == 0 {
+			return h, nil
+		}
+	}
+
+	// the name is parsed into copies, so that a layout which fails part way leaves nothing
+	// behind
+	casava, legacy := h, h
+	switch {
+	case casava.parseCasava(fields[0]):
+		h = casava
+		h.Format = CasavaHeader
+		fields = fields[1:]
+		if len(fields) > 0 && h.parseCasavaComment(fields[0]) {
+			fields = fields[1:]
+		}
+	case legacy.parseLegacy(fields[0]):
+		h = legacy
+		h.Format = LegacyIlluminaHeader
+		fields = fields[1:]
+	case h.Accession == "":
+		return h, fmt.Errorf("unrecognized read header %q", id)
+	}
+
+	h.Comment = strings.Join(fields, " ")
+	return h, nil
+}
+
+// isSRAAccession reports whether the word is an SRA, ENA or DDBJ run accession, with or
+// without a spot number
+func isSRAAccession(word string) bool {
+	if len(word) < 4 || word[1:3] != "RR" || strings.IndexByte("SED", word[0]) < 0 {
+		return false
+	}
+	rest := word[3:]
+	for {
+		k := strings.IndexByte(rest, '.')
+		if k < 0 {
+			return isDigits(rest)
+		}
+		if !isDigits(rest[:k]) {
+			return false
+		}
+		rest = rest[k+1:]
+	}
+}
+
+// atois parses each of the fields as a non-negative integer, into the ints, reporting
+// whether they all parsed
+func atois(fields []string, ints ...*int) bool {
+	for k, field := range fields {
+		n, ok := count(field)
+		if !ok {
+			return false
+		}
+		*ints[k] = n
+	}
+	return true
+}
+
+// counts reports whether each of the fields is a non-negative integer
+func counts(fields ...string) bool {
+	for _, field := range fields {
+		if _, ok := count(field); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// parseCasava parses a Casava 1.8 read name,
+// instrument:run:flowcell:lane:tile:x:y[:UMI]
+func (h *ReadHeader) parseCasava(name string) bool {
+	fields := strings.Split(name, ":")
+	if len(fields) != 7 && len(fields) != 8 {
+		return false
+	}
+	if fields[0] == "" || fields[2] == "" || !atois([]string{fields[1], fields[3], fields[4], fields[5], fields[6]}, &h.Run, &h.Lane, &h.Tile, &h.X, &h.Y) {
+		return false
+	}
+	h.Instrument, h.Flowcell = fields[0], fields[2]
+	if len(fields) == 8 {
+		h.UMI = fields[7]
+	}
+	return true
+}
+
+// parseCasavaComment parses the first word of the comment of a Casava 1.8 header,
+// read:filtered:control:index
+func (h *ReadHeader) parseCasavaComment(comment string) bool {
+	fields := strings.Split(comment, ":")
+	if len(fields) != 4 || (fields[1] != "Y" && fields[1] != "N") {
+		return false
+	}
+	var read, control int
+	if !atois(fields[:1], &read) || !atois(fields[2:3], &control) {
+		return false
+	}
+	h.Read, h.Filtered, h.Control, h.Index = read, fields[1] == "Y", control, fields[3]
+	return true
+}
+
+// parseLegacy parses a read name from before Casava 1.8,
+// instrument:lane:tile:x:y[#index][/read]
+func (h *ReadHeader) parseLegacy(name string) bool {
+	var read int
+	if k := strings.LastIndexByte(name, '/'); k >= 0 {
+		if !atois([]string{name[k+1:]}, &read) {
+			return false
+		}
+		name = name[:k]
+	}
+	var index string
+	if k := strings.IndexByte(name, '#'); k >= 0 {
+		name, index = name[:k], name[k+1:]
+	}
+
+	fields := strings.Split(name, ":")
+	if len(fields) != 5 || fields[0] == "" || !atois(fields[1:], &h.Lane, &h.Tile, &h.X, &h.Y) {
+		return false
+	}
+	h.Instrument, h.Index, h.Read = fields[0], index, read
+	return true
+}
+
+// Name returns the Illumina read name of the header, the word after the "@" (and the SRA
+// accession), or "" for an OtherHeader
+func (h ReadHeader) Name() string {
+	switch h.Format {
+	case CasavaHeader:
+		name := fmt.Sprintf("%s:%d:%s:%d:%d:%d:%d", h.Instrument, h.Run, h.Flowcell, h.Lane, h.Tile, h.X, h.Y)
+		if h.UMI != "" {
+			name += ":" + h.UMI
+		}
+		return name
+	case LegacyIlluminaHeader:
+		name := fmt.Sprintf("%s:%d:%d:%d:%d", h.Instrument, h.Lane, h.Tile, h.X, h.Y)
+		if h.Index != "" {
+			name += "#" + h.Index
+		}
+		if h.Read > 0 {
+			name += "/" + strconv.Itoa(h.Read)
+		}
+		return name
+	}
+	return ""
+}
+
+// String returns the header as the ID line of a FASTQ read, with its leading "@". A
+// header parsed by ParseReadHeader is written back as it was, apart from the spacing.
+// The read information of a Casava header is only written if its Read is set.
+func (h ReadHeader) String() string {
+	var words []string
+	if h.Accession != "" {
+		words = append(words, h.Accession)
+	}
+	if name := h.Name(); name != "" {
+		words = append(words, name)
+	}
+	if h.Format == CasavaHeader && h.Read > 0 {
+		filtered := "N"
+		if h.Filtered {
+			filtered = "Y"
+		}
+		words = append(words, fmt.Sprintf("%d:%s:%d:%s", h.Read, filtered, h.Control, h.Index))
+	}
+	if h.Comment != "" {
+		words = append(words, h.Comment)
+	}
+	if h.Accession != "" && h.Length > 0 {
+		words = append(words, "length="+strconv.Itoa(h.Length))
+	}
+	return "@" + strings.Join(words, " ")
+}
+
+// Header parses the ID of the read with ParseReadHeader
+func (r FASTQRead) Header() (ReadHeader, error) {
+	return ParseReadHeader(r.ID)
+}
+
+// ExcludeTilesFilter rejects reads from any of the tiles, such as tiles with bubbles or
+// other flowcell defects. Reads whose headers cannot be parsed, or which do not give a
+// tile, are kept.
+func ExcludeTilesFilter(tiles ...int) ReadFilter {
+	excluded := make(map[int]bool, len(tiles))
+	for _, tile := range tiles {
+		excluded[tile] = true
+	}
+	return ReadFilter{"excluded_tile", func(r FASTQRead) bool {
+		tile, ok := readTile(r.ID)
+		return !ok || !excluded[tile]
+	}}
+}
+
+// readTile finds the tile of a read from its ID, as ParseReadHeader would, without
+// parsing the rest of the header or allocating. It only looks at the Illumina read name:
+// the first word, or the second after an SRA accession. It returns false if the name is
+// in neither layout.
+func readTile(id string) (int, bool) {
+	name, rest := nextWord(strings.TrimPrefix(id, "@"))
+	if isSRAAccession(name) {
+		name, _ = nextWord(rest)
+	}
+
+	var fields [9]string
+
+	// instrument:run:flowcell:lane:tile:x:y[:UMI]
+	if n := splitName(name, fields[:]); (n == 7 || n == 8) && fields[0] != "" && fields[2] != "" &&
+		counts(fields[1], fields[3], fields[5], fields[6]) {
+		if tile, ok := count(fields[4]); ok {
+			return tile, true
+		}
+	}
+
+	// instrument:lane:tile:x:y[#index][/read]
+	if k := strings.LastIndexByte(name, '/'); k >= 0 {
+		if !counts(name[k+1:]) {
+			return 0, false
+		}
+		name = name[:k]
+	}
+	if k := strings.IndexByte(name, '#'); k >= 0 {
+		name = name[:k]
+	}
+	if n := splitName(name, fields[:]); n == 5 && fields[0] != "" && counts(fields[1], fields[3], fields[4]) {
+		return count(fields[2])
+	}
+	return 0, false
+}
+
+// nextWord returns the first word of s, and the rest of s after it
+func nextWord(s string) (word string, rest string) {
+	start := 0
+	for start < len(s) && isSpace(s[start]) {
+		start++
+	}
+	end := start
+	for end < len(s) && !isSpace(s[end]) {
+		end++
+	}
+	return s[start:end], s[end:]
+}
+
+// isSpace reports whether c is an ASCII white space character
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+// splitName splits a read name at each ':' into fields, returning the number of fields,
+// or -1 if there are more than fit
+func splitName(name string, fields []string) int {
+	n := 0
+	for {
+		if n == len(fields) {
+			return -1
+		}
+		k := strings.IndexByte(name, ':')
+		if k < 0 {
+			fields[n] = name
+			return n + 1
+		}
+		fields[n], name = name[:k], name[k+1:]
+		n++
+	}
+}
+
+// count parses a field of plain decimal digits (with no sign), without allocating an
+// error for a field which is not one
+func count(field string) (int, bool) {
+	if len(field) > 18 || !isDigits(field) {
+		return 0, false
+	}
+	n, _ := strconv.Atoi(field)
+	return n, true
+}
+
+// isDigits reports whether s is a non-empty run of decimal digits
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for k := 0; k < len(s); k++ {
+		if s[k] < '0' || s[k] > '9' {
+			return false
+		}
+	}
+	return true
+}
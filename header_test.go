@@ -0,0 +1,116 @@
+package gobioinfo
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// func ParseReadHeader(id string) (ReadHeader, error)
+// func (h ReadHeader) String() string
+func TestParseReadHeader(t *testing.T) {
+	fmt.Println("testing ParseReadHeader() and ReadHeader.String()...")
+
+	type testPair struct {
+		id     string
+		header ReadHeader
+	}
+
+	testSuite := []testPair{
+		{"@HWI-ST560:155:C574EACXX:3:1101:1159:1937 1:N:0:", ReadHeader{Format: CasavaHeader,
+			Instrument: "HWI-ST560", Run: 155, Flowcell: "C574EACXX", Lane: 3, Tile: 1101, X: 1159, Y: 1937, Read: 1}},
+		{"@NB501234:12:HXXXXBGXX:1:11101:10000:1043 2:Y:18:ATCACG+GATTAC", ReadHeader{Format: CasavaHeader,
+			Instrument: "NB501234", Run: 12, Flowcell: "HXXXXBGXX", Lane: 1, Tile: 11101, X: 10000, Y: 1043,
+			Read: 2, Filtered: true, Control: 18, Index: "ATCACG+GATTAC"}},
+		{"@M00123:7:000000000-A1B2C:1:1101:15589:1331:ACGTACGT 1:N:0:3 BC:Z:x", ReadHeader{Format: CasavaHeader,
+			Instrument: "M00123", Run: 7, Flowcell: "000000000-A1B2C", Lane: 1, Tile: 1101, X: 15589, Y: 1331,
+			UMI: "ACGTACGT", Read: 1, Index: "3", Comment: "BC:Z:x"}},
+		{"@A00123:8:HFLOWCELL:2:1234:5678:9012", ReadHeader{Format: CasavaHeader,
+			Instrument: "A00123", Run: 8, Flowcell: "HFLOWCELL", Lane: 2, Tile: 1234, X: 5678, Y: 9012}},
+		{"@HWUSI-EAS100R:6:73:941:1973#0/1", ReadHeader{Format: LegacyIlluminaHeader,
+			Instrument: "HWUSI-EAS100R", Lane: 6, Tile: 73, X: 941, Y: 1973, Index: "0", Read: 1}},
+		{"@HWUSI-EAS100R:6:73:941:1973#ACGTAC", ReadHeader{Format: LegacyIlluminaHeader,
+			Instrument: "HWUSI-EAS100R", Lane: 6, Tile: 73, X: 941, Y: 1973, Index: "ACGTAC"}},
+		{"@SRR001666.1 071112_SLXA-EAS1_s_7:5:1:817:345 length=36", ReadHeader{Format: LegacyIlluminaHeader,
+			Accession: "SRR001666.1", Instrument: "071112_SLXA-EAS1_s_7", Lane: 5, Tile: 1, X: 817, Y: 345, Length: 36}},
+		{"@ERR123456.7 HWI-ST560:155:C574EACXX:3:1101:1159:1937 2:N:0:ACGT length=100", ReadHeader{Format: CasavaHeader,
+			Accession: "ERR123456.7", Instrument: "HWI-ST560", Run: 155, Flowcell: "C574EACXX", Lane: 3, Tile: 1101, X: 1159, Y: 1937,
+			Read: 2, Index: "ACGT", Length: 100}},
+		{"@SRR5.3 read3 length=12", ReadHeader{Accession: "SRR5.3", Comment: "read3", Length: 12}},
+		{"@DRR000001.1.2", ReadHeader{Accession: "DRR000001.1.2"}},
+		// a Casava name with a comment which is not Illumina's is kept whole
+		{"@A00123:8:HFLOWCELL:2:1234:5678:9012 1:N:0", ReadHeader{Format: CasavaHeader,
+			Instrument: "A00123", Run: 8, Flowcell: "HFLOWCELL", Lane: 2, Tile: 1234, X: 5678, Y: 9012, Comment: "1:N:0"}},
+	}
+
+	for i, test := range testSuite {
+		h, err := ParseReadHeader(test.id)
+		if err != nil {
+			t.Errorf("test %d: expected no error, but got %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(h, test.header) {
+			t.Errorf("test %d: expected %+v, but got %+v", i, test.header, h)
+		}
+		if h.String() != test.id {
+			t.Errorf("test %d: expected to write %q, but got %q", i, test.id, h.String())
+		}
+	}
+
+	for i, id := range []string{"", "@", "@read42/1", "@HWI:1:FC:3:x:1:2 1:N:0:", "@HWI:6:73:941", "@SRR1.1 x length=-1", "@SRRX.1 a:1:2:3:4",
+		"@HWI:1:FC:3:+4:1:2 1:N:0:", "@HWUSI-EAS100R:6:+73:941:1973#0/1", "@SRR1.1 x length=+36"} {
+		if h, err := ParseReadHeader(id); err == nil {
+			t.Errorf("test %d: expected an error for %q, but got %+v", i, id, h)
+		}
+	}
+}
+
+// func ExcludeTilesFilter(tiles ...int) ReadFilter
+// func readTile(id string) (int, bool)
+func TestExcludeTilesFilter(t *testing.T) {
+	fmt.Println("testing ExcludeTilesFilter()...")
+
+	filter := ExcludeTilesFilter(1101, 2204)
+
+	type testPair struct {
+		id   string
+		keep bool
+	}
+
+	testSuite := []testPair{
+		{"@HWI-ST560:155:C574EACXX:3:1101:1159:1937 1:N:0:", false},
+		{"@HWI-ST560:155:C574EACXX:3:1102:1159:1937 1:N:0:", true},
+		{"@HWUSI-EAS100R:6:2204:941:1973#0/1", false},
+		{"@SRR5.3 read3 length=12", true},
+		{"@ERR123456.7 HWI-ST560:155:C574EACXX:3:2204:1159:1937 2:N:0:ACGT length=100", false},
+		{"@HWUSI-EAS100R:6:2204:941:1973#0/x", true},
+		// names in neither layout, and not SRA accessions
+		{"@read42", true},
+		{"@some:thing", true},
+		{"@a:b:c:d:1101:f:g", true},
+		{"@HWI-ST560:155:C574EACXX:3:+1101:1159:1937 1:N:0:", true},
+		{"@HWUSI-EAS100R:+6:2204:941:1973#0/1", true},
+	}
+
+	for i, test := range testSuite {
+		r := NewFASTQRead(test.id, []rune("ACGT"), "+", []rune("IIII"))
+		if filter.Keep(r) != test.keep {
+			t.Errorf("test %d: expected %v for %q, but got %v", i, test.keep, test.id, !test.keep)
+		}
+	}
+
+	// the tile is found as ParseReadHeader finds it, without allocating
+	for i, id := range []string{"@HWI-ST560:155:C574EACXX:3:1101:1159:1937 1:N:0:", "@M00123:7:000000000-A1B2C:1:1101:15589:1331:ACGTACGT 1:N:0:3",
+		"@HWUSI-EAS100R:6:73:941:1973#0/1", "@HWUSI-EAS100R:6:73:941:1973#ACGTAC", "@SRR001666.1 071112_SLXA-EAS1_s_7:5:1:817:345 length=36",
+		"@SRR5.3 read3 length=12", "@DRR000001.1.2", "", "@", "@read42/1", "@HWI:1:FC:3:x:1:2 1:N:0:", "@HWI:6:73:941", "@SRRX.1 a:1:2:3:4",
+		"@some:thing", "@\tHWI:1:FC:3:+4:1:2", "@HWI:1:FC:3:+4:1:2", "@HWUSI-EAS100R:6:+73:941:1973#0/1"} {
+		h, err := ParseReadHeader(id)
+		tile, ok := readTile(id)
+		if expected := err == nil && h.Format != OtherHeader; ok != expected || (ok && tile != h.Tile) {
+			t.Errorf("test %d: expected readTile(%q) to give %d, %v, but got %d, %v", i, id, h.Tile, expected, tile, ok)
+		}
+		if allocs := testing.AllocsPerRun(10, func() { readTile(id) }); allocs > 0 {
+			t.Errorf("test %d: expected readTile(%q) not to allocate, but got %v allocations", i, id, allocs)
+		}
+	}
+}
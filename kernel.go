@@ -0,0 +1,561 @@
+package gobioinfo
+
+/*
+The inner loop shared by the aligners.
+
+The alignment matrices are filled one row (query base) at a time, each from the row above
+and the cell to the left, in plain loops over flat slices with nothing allocated per
+cell. Rather than scoring every pair of bases as it is met, the score and classification
+of a query base against every subject base is looked up in a profile, worked out the
+first time that base is seen in the query; reads are made of a handful of different
+bases, so this replaces nearly all of the calls to Scoring.pair.
+
+align keeps the movements (D) of every cell for the traceback, while alignLinear only
+keeps a few rows and columns and alignScore, which has no traceback, only the row being
+filled and the one above it, but all of them fill the matrices in exactly the same way. Along
+with the movement chosen for H, each cell of D records whether I and J extend a gap or
+open one, so that the traceback can follow a gap through the gap matrix it was scored
+in rather than dropping back into H after one step.
+
+The buffers only ever grow, so an aligner can be reset to a new subject and reused
+without allocating, as an Aligner does.
+*/
+
+// aligner holds the sequences, scoring and working state of an alignment
+type aligner struct {
+	q       NucleotideSequence
+	s       NucleotideSequence
+	sc      Scoring
+	mode    AlignMode
+	ends    FreeEnds // the free ends, after taking the mode into account
+	edgeGap int      // the value the gap matrices start from along the edges
+	local   bool
+	h       int // gap opening penalty
+	g       int // gap extension penalty
+	ties    TieBreak
+	band    *Band
+
+	// the first row (indexed by i) and first column (indexed by j) of the matrices
+	rowH, rowI, rowJ []int
+	rowD             []uint8
+	colH, colI, colJ []int
+	colD             []uint8
+
+	// the profiles of the ASCII query bases seen so far, and the scores of the query bases
+	// against each ASCII subject base if they have been worked out in advance
+	profiles [128]*profile
+	pairs    [128]*pairScores
+
+	// what is left of the buffers that new profiles are cut from
+	spareProfiles []profile
+	spareScores   []int
+	spareOrigins  []uint8
+
+	// working rows for fill and findEnd, and the movements kept by align
+	prevH, prevJ, curH, curI, curJ []int
+	curD                           []uint8
+	lastRow, lastColumn            []int
+	D                              []uint8
+
+	revCIGAR []int
+	last     matrixPosition // the last cell added to revCIGAR
+	repr     []byte         // the strings of the last alignment, as they are built
+}
+
+// profile holds the score and classification (match, mismatch or neutral) of a query base
+// against each base of the subject, indexed from 1 like the columns of the matrices
+type profile struct {
+	scores  []int
+	origins []uint8
+	ready   bool // whether scores and origins are for the current subject
+}
+
+// pairScores holds the score and classification of a query base against each ASCII base
+type pairScores struct {
+	scores  [128]int
+	origins [128]uint8
+}
+
+// newAligner sets up the alignment of the query q to the subject s
+func newAligner(q NucleotideSequence, s NucleotideSequence, mode AlignMode, ends FreeEnds, sc Scoring, ties TieBreak, band *Band) *aligner {
+	ends, edgeGap := modeEdges(mode, ends)
+	return &aligner{
+		q:       q,
+		s:       s,
+		sc:      sc,
+		mode:    mode,
+		ends:    ends,
+		edgeGap: edgeGap,
+		local:   mode == Local,
+		h:       sc.GapOpen,
+		g:       sc.GapExtend,
+		ties:    ties,
+		band:    band,
+	}
+}
+
+// reset sets the aligner up for a new subject, keeping its buffers
+func (a *aligner) reset(s NucleotideSequence) {
+	a.s = s
+	for _, p := range a.profiles {
+		if p != nil {
+			p.ready = false
+		}
+	}
+	a.revCIGAR = a.revCIGAR[:0]
+	a.last = matrixPosition{}
+}
+
+// precompute works out the scores of each query base against every ASCII base, so that
+// the profiles for each new subject are quick to fill in
+func (a *aligner) precompute() {
+	for _, base := range a.q {
+		if base < 0 || base >= 128 || a.pairs[base] != nil {
+			continue
+		}
+		pairs := &pairScores{}
+		for c := range pairs.scores {
+			score, origin := a.sc.pair(rune(c), base)
+			pairs.scores[c], pairs.origins[c] = score, uint8(origin)
+		}
+		a.pairs[base] = pairs
+	}
+}
+
+// profile returns the profile of a query base
+func (a *aligner) profile(base rune) *profile {
+	ascii := base >= 0 && base < 128
+	if ascii && a.profiles[base] != nil && a.profiles[base].ready {
+		return a.profiles[base]
+	}
+
+	// new profiles are cut from buffers with room for A, C, G, T and N, so that the
+	// profiles of a read cost a few allocations between them
+	n := len(a.s) + 1
+	var p *profile
+	if ascii {
+		p = a.profiles[base]
+	}
+	if p == nil {
+		if len(a.spareProfiles) == 0 {
+			a.spareProfiles = make([]profile, 5)
+		}
+		p, a.spareProfiles = &a.spareProfiles[0], a.spareProfiles[1:]
+		if ascii {
+			a.profiles[base] = p
+		}
+	}
+	if cap(p.scores) < n {
+		if cap(a.spareScores) < n {
+			a.spareScores, a.spareOrigins = make([]int, 5*n), make([]uint8, 5*n)
+		}
+		p.scores, a.spareScores = a.spareScores[:n:n], a.spareScores[n:]
+		p.origins, a.spareOrigins = a.spareOrigins[:n:n], a.spareOrigins[n:]
+	}
+	p.scores, p.origins = p.scores[:n], p.origins[:n]
+
+	var pairs *pairScores
+	if ascii {
+		pairs = a.pairs[base]
+	}
+	for i := 1; i <= len(a.s); i++ {
+		c := a.s[i-1]
+		if pairs != nil && c >= 0 && c < 128 {
+			p.scores[i], p.origins[i] = pairs.scores[c], pairs.origins[c]
+			continue
+		}
+		score, origin := a.sc.pair(c, base)
+		p.scores[i], p.origins[i] = score, uint8(origin)
+	}
+
+	p.ready = true
+	return p
+}
+
+// growInts returns buf with n elements, only allocating if it is too small
+func growInts(buf []int, n int) []int {
+	if cap(buf) < n {
+		return make([]int, n)
+	}
+	return buf[:n]
+}
+
+// growRows gives each of the rows n elements, cutting those which are too small from one
+// new buffer, so that they cost at most one allocation between them
+func growRows(n int, rows ...*[]int) {
+	short := 0
+	for _, row := range rows {
+		if cap(*row) < n {
+			short++
+		}
+	}
+	var buf []int
+	if short > 0 {
+		buf = make([]int, short*n)
+	}
+	for _, row := range rows {
+		if cap(*row) < n {
+			*row, buf = buf[:n:n], buf[n:]
+		} else {
+			*row = (*row)[:n]
+		}
+	}
+}
+
+// growBytes returns buf with n elements, only allocating if it is too small
+func growBytes(buf []uint8, n int) []uint8 {
+	if cap(buf) < n {
+		return make([]uint8, n)
+	}
+	return buf[:n]
+}
+
+// outside reports whether a cell lies outside of the band, if there is one
+func (a *aligner) outside(i int, j int) bool {
+	return a.band != nil && a.band.outside(i, j)
+}
+
+// edges fills in the first row and column of the matrices. Along a free edge the
+// matrices are 0 (or start from edgeGap), and along a penalized edge the gap penalties
+// add up in H and the gap matrix running along it; the other gap matrix can not be
+// reached there, as a gap across the edge would skip the bases before it for free. Cells
+// outside of a band can not be reached.
+func (a *aligner) edges() {
+
+	lenI := len(a.s) + 1
+	lenJ := len(a.q) + 1
+
+	growRows(lenI, &a.rowH, &a.rowI, &a.rowJ)
+	growRows(lenJ, &a.colH, &a.colI, &a.colJ)
+	a.rowD, a.colD = growBytes(a.rowD, lenI), growBytes(a.colD, lenJ)
+
+	a.rowH[0], a.rowI[0], a.rowJ[0], a.rowD[0] = 0, a.edgeGap, a.edgeGap, gap
+	if a.outside(0, 0) {
+		a.rowH[0], a.rowI[0], a.rowJ[0] = negInf, negInf, negInf
+	}
+	for i := 1; i < lenI; i++ {
+		switch {
+		case a.outside(i, 0):
+			a.rowH[i], a.rowI[i], a.rowJ[i], a.rowD[i] = negInf, negInf, negInf, gap
+		case a.ends.SubjectStart:
+			a.rowH[i], a.rowI[i], a.rowJ[i], a.rowD[i] = 0, a.edgeGap, a.edgeGap, gap
+		default:
+			a.rowI[i], a.rowD[i] = a.rowH[i-1]-a.h, insI
+			if extend := a.rowI[i-1] - a.g; extend > a.rowI[i] {
+				a.rowI[i], a.rowD[i] = extend, insI|extendI
+			}
+			a.rowJ[i] = negInf
+			a.rowH[i] = a.rowI[i]
+		}
+	}
+
+	a.colH[0], a.colI[0], a.colJ[0], a.colD[0] = a.rowH[0], a.rowI[0], a.rowJ[0], gap
+	for j := 1; j < lenJ; j++ {
+		switch {
+		case a.outside(0, j):
+			a.colH[j], a.colI[j], a.colJ[j], a.colD[j] = negInf, negInf, negInf, gap
+		case a.ends.QueryStart:
+			a.colH[j], a.colI[j], a.colJ[j], a.colD[j] = 0, a.edgeGap, a.edgeGap, gap
+		default:
+			a.colI[j] = negInf
+			a.colJ[j], a.colD[j] = a.colH[j-1]-a.h, insJ
+			if extend := a.colJ[j-1] - a.g; extend > a.colJ[j] {
+				a.colJ[j], a.colD[j] = extend, insJ|extendJ
+			}
+			a.colH[j] = a.colJ[j]
+		}
+	}
+}
+
+// fill computes the matrices over rows r0 to r1 and columns c0 to c1 (counting from 1),
+// given H and J along row r0-1 in topH and topJ (indexed from column c0-1) and H and I
+// along column c0-1 in leftH and leftI (indexed from row r0). visit is called with each
+// row in turn, indexed from column c0-1, and must not keep hold of it. With a band, only
+// the band and the unreachable cell either side of it are filled in each row, and the
+// rest of the row is left as it was.
+func (a *aligner) fill(r0 int, c0 int, r1 int, c1 int, topH []int, topJ []int, leftH []int, leftI []int, visit func(j int, H []int, I []int, J []int, D []uint8)) {
+
+	width := c1 - c0 + 2
+
+	growRows(width, &a.prevH, &a.prevJ, &a.curH, &a.curI, &a.curJ)
+	prevH, prevJ := a.prevH, a.prevJ
+	copy(prevH, topH[:width])
+	copy(prevJ, topJ[:width])
+	curH, curI, curJ, curD := a.curH, a.curI, a.curJ, growBytes(a.curD, width)
+
+	h, g, local, gapsFirst := a.h, a.g, a.local, a.ties.GapsFirst
+
+	for j := r0; j <= r1; j++ {
+		curH[0] = leftH[j-r0]
+		curI[0] = leftI[j-r0]
+
+		p := a.profile(a.q[j-1])
+		scores := p.scores[c0-1 : c1+1]
+		origins := p.origins[c0-1 : c1+1]
+
+		first, last := 1, width-1
+		if a.band != nil {
+			// the columns of the band in this row, as offsets from c0-1
+			lo := j + a.band.Offset - a.band.Width - c0 + 1
+			hi := j + a.band.Offset + a.band.Width - c0 + 1
+			if k := lo - 1; k >= first && k <= last {
+				curH[k], curI[k], curJ[k], curD[k] = negInf, negInf, negInf, gap
+			}
+			if k := hi + 1; k >= first && k <= last {
+				curH[k], curI[k], curJ[k], curD[k] = negInf, negInf, negInf, gap
+			}
+			if lo > first {
+				first = lo
+			}
+			if hi < last {
+				last = hi
+			}
+		}
+
+		// the rows are cut to the same length, so that the loop is free of bounds checks,
+		// and the cells to the left and above to the left are carried along
+		n := last + 1
+		if first < n {
+			rowH, rowI, rowJ, rowD := curH[:n], curI[:n], curJ[:n], curD[:n]
+			upH, upJ, rowScores, rowOrigins := prevH[:n], prevJ[:n], scores[:n], origins[:n]
+			left, leftI, diagonal := rowH[first-1], rowI[first-1], upH[first-1]
+
+			for k := first; k < n; k++ {
+				// a gap is only extended if that scores more than opening one
+				var extended uint8
+				iScore := left - h
+				if extend := leftI - g; extend > iScore {
+					iScore, extended = extend, extendI
+				}
+				up := upH[k]
+				jScore := up - h
+				if extend := upJ[k] - g; extend > jScore {
+					jScore, extended = extend, extended|extendJ
+				}
+
+				var best int
+				var move uint8
+				if gapsFirst {
+					best, move = iScore, insI
+					if jScore > best {
+						best, move = jScore, insJ
+					}
+					if match := diagonal + rowScores[k]; match > best {
+						best, move = match, rowOrigins[k]
+					}
+				} else {
+					best, move = diagonal+rowScores[k], rowOrigins[k]
+					if iScore > best {
+						best, move = iScore, insI
+					}
+					if jScore > best {
+						best, move = jScore, insJ
+					}
+				}
+				if local && best <= 0 {
+					best, move = 0, gap
+				}
+
+				rowH[k], rowI[k], rowJ[k], rowD[k] = best, iScore, jScore, move|extended
+				left, leftI, diagonal = best, iScore, up
+			}
+		}
+
+		visit(j, curH, curI, curJ, curD)
+
+		prevH, curH = curH, prevH
+		prevJ, curJ = curJ, prevJ
+	}
+
+	a.prevH, a.prevJ, a.curH, a.curI, a.curJ, a.curD = prevH, prevJ, curH, curI, curJ, curD
+}
+
+// matrices fills the whole of the matrices and returns H, I, J and D, each held row by
+// row (len(s)+1 cells to a row) like D in align
+func (a *aligner) matrices() ([]int, []int, []int, []uint8) {
+
+	lenI := len(a.s) + 1
+	lenJ := len(a.q) + 1
+
+	a.edges()
+
+	H, I, J, D := make([]int, lenI*lenJ), make([]int, lenI*lenJ), make([]int, lenI*lenJ), make([]uint8, lenI*lenJ)
+	copy(H, a.rowH)
+	copy(I, a.rowI)
+	copy(J, a.rowJ)
+	copy(D, a.rowD)
+	for j := 1; j < lenJ; j++ {
+		H[j*lenI], I[j*lenI], J[j*lenI], D[j*lenI] = a.colH[j], a.colI[j], a.colJ[j], a.colD[j]
+	}
+
+	a.fill(1, 1, lenJ-1, lenI-1, a.rowH, a.rowJ, a.colH[1:], a.colI[1:], func(j int, rowH []int, rowI []int, rowJ []int, rowD []uint8) {
+		copy(H[j*lenI+1:(j+1)*lenI], rowH[1:])
+		copy(I[j*lenI+1:(j+1)*lenI], rowI[1:])
+		copy(J[j*lenI+1:(j+1)*lenI], rowJ[1:])
+		copy(D[j*lenI+1:(j+1)*lenI], rowD[1:])
+	})
+
+	return H, I, J, D
+}
+
+// findEnd fills the whole of the matrices, handing each row of movements to keep (unless
+// it is nil), and returns the cell where the alignment ends and its score: the bottom
+// right corner for a global alignment, the best cell of the last row or column (when the
+// end of the subject or query is free) for a semi-global alignment, and the best cell
+// anywhere for a local alignment, with ties broken by betterEnd
+func (a *aligner) findEnd(keep func(j int, D []uint8)) (matrixPosition, int) {
+
+	lenI := len(a.s) + 1
+	lenJ := len(a.q) + 1
+
+	// cells outside of a band are never the end of the alignment
+	score := func(row []int, i int, j int) int {
+		if a.outside(i, j) {
+			return negInf
+		}
+		return row[i]
+	}
+
+	lastRow, lastColumn := growInts(a.lastRow, lenI), growInts(a.lastColumn, lenJ)
+	a.lastRow, a.lastColumn = lastRow, lastColumn
+	copy(lastRow, a.rowH)
+	copy(lastColumn, a.colH)
+	lastColumn[0] = score(a.rowH, lenI-1, 0)
+
+	maxScore := 0
+	maxPosition := matrixPosition{}
+
+	a.fill(1, 1, lenJ-1, lenI-1, a.rowH, a.rowJ, a.colH[1:], a.colI[1:], func(j int, H []int, I []int, J []int, D []uint8) {
+		if keep != nil {
+			keep(j, D)
+		}
+		lastColumn[j] = score(H, lenI-1, j)
+		if j == lenJ-1 {
+			copy(lastRow, H)
+		}
+		if a.local {
+			// only the cells of a band can end the alignment, so only they are scanned
+			first, last := 1, lenI-1
+			if a.band != nil {
+				if lo := j + a.band.Offset - a.band.Width; lo > first {
+					first = lo
+				}
+				if hi := j + a.band.Offset + a.band.Width; hi < last {
+					last = hi
+				}
+			}
+			for i := first; i <= last; i++ {
+				if h := H[i]; h >= maxScore && a.betterEnd(h, matrixPosition{i: i, j: j}, maxScore, maxPosition) {
+					maxScore = h
+					maxPosition = matrixPosition{i: i, j: j}
+				}
+			}
+		}
+	})
+
+	if a.local {
+		return maxPosition, maxScore
+	}
+
+	maxScore = score(lastRow, lenI-1, lenJ-1)
+	maxPosition = matrixPosition{i: lenI - 1, j: lenJ - 1}
+
+	if a.mode == SemiGlobal {
+		if a.ends.SubjectEnd {
+			for i := 0; i < lenI; i++ {
+				if h := score(lastRow, i, lenJ-1); a.betterEnd(h, matrixPosition{i: i, j: lenJ - 1}, maxScore, maxPosition) {
+					maxScore = h
+					maxPosition = matrixPosition{i: i, j: lenJ - 1}
+				}
+			}
+		}
+		if a.ends.QueryEnd {
+			for j := 0; j < lenJ; j++ {
+				if a.betterEnd(lastColumn[j], matrixPosition{i: lenI - 1, j: j}, maxScore, maxPosition) {
+					maxScore = lastColumn[j]
+					maxPosition = matrixPosition{i: lenI - 1, j: j}
+				}
+			}
+		}
+	}
+
+	return maxPosition, maxScore
+}
+
+// betterEnd reports whether an alignment ending at p with the given score is preferred to
+// the best found so far, ending at best with bestScore
+func (a *aligner) betterEnd(score int, p matrixPosition, bestScore int, best matrixPosition) bool {
+	return a.ties.betterEnd(a.local, score, p, bestScore, best)
+}
+
+// betterEnd reports whether an alignment ending at p with the given score is preferred to
+// the best found so far, ending at best with bestScore. Ends are met in the order that
+// FirstEnd describes, so that ties only replace the best end for LeftmostEnd and
+// RightmostEnd.
+func (t TieBreak) betterEnd(local bool, score int, p matrixPosition, bestScore int, best matrixPosition) bool {
+	if score != bestScore {
+		return score > bestScore
+	}
+	// an end has to be reachable, and a local alignment has to score more than nothing
+	if score <= negInf || (local && score <= 0) {
+		return false
+	}
+	switch t.Ends {
+	case LeftmostEnd:
+		return p.i < best.i || (p.i == best.i && p.j < best.j)
+	case RightmostEnd:
+		return p.i > best.i || (p.i == best.i && p.j > best.j)
+	}
+	return false
+}
+
+// traceState is the matrix the traceback is following at a cell: H, or the gap matrix I
+// or J while it is inside a gap
+type traceState uint8
+
+// Traceback states
+const (
+	inH traceState = iota
+	inI
+	inJ
+)
+
+// traceStep takes one step of the traceback from the cell p of the matrix state, where d
+// holds the movements of p. It returns the movement to add to the CIGAR (0 for a step from
+// H into a gap matrix, which stays at p) and the cell and matrix the traceback goes on
+// to, or start is true if the alignment starts at p.
+func (a *aligner) traceStep(p matrixPosition, state traceState, d uint8) (move uint8, next matrixPosition, nextState traceState, start bool) {
+	switch state {
+	case inI:
+		// the gap matrices only hold the value a gap starts from along the first row
+		// and column
+		if p.i == 0 {
+			return 0, p, state, true
+		}
+		if d&extendI == 0 {
+			state = inH
+		}
+		return insI, matrixPosition{i: p.i - 1, j: p.j}, state, false
+	case inJ:
+		if p.j == 0 {
+			return 0, p, state, true
+		}
+		if d&extendJ == 0 {
+			state = inH
+		}
+		return insJ, matrixPosition{i: p.i, j: p.j - 1}, state, false
+	}
+
+	if a.outside(p.i, p.j) {
+		return 0, p, state, true
+	}
+	switch d & moveBits {
+	case gap:
+		return 0, p, state, true
+	case insI:
+		return 0, p, inI, false
+	case insJ:
+		return 0, p, inJ, false
+	}
+	return d & moveBits, matrixPosition{i: p.i - 1, j: p.j - 1}, inH, false
+}
@@ -0,0 +1,195 @@
+package gobioinfo
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"testing"
+)
+
+// func (a *aligner) profile(base rune) *profile
+func TestAlignerProfile(t *testing.T) {
+	fmt.Println("testing aligner.profile()...")
+
+	subject := NucleotideSequence("ACGTNRacgt")
+
+	for _, sc := range []Scoring{DefaultScoring, NUC44Scoring} {
+		a := newAligner(NucleotideSequence("A"), subject, SemiGlobal, AllEndsFree, sc, TieBreak{}, nil)
+		for _, base := range []rune{'A', 'c', 'N', 'Y', 'U', 'é'} {
+			p := a.profile(base)
+			if a.profile(base) != p && base < 128 {
+				t.Errorf("expected the profile of %q to be kept", base)
+			}
+			for i := 1; i <= len(subject); i++ {
+				score, origin := sc.pair(subject[i-1], base)
+				if p.scores[i] != score || int(p.origins[i]) != origin {
+					t.Errorf("%q against %q: expected %d (%d), but got %d (%d)",
+						base, subject[i-1], score, origin, p.scores[i], p.origins[i])
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkLocalAlign(b *testing.B) {
+	reads := benchmarkReads(b)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, read := range reads {
+			read.Sequence.LocalAlign(benchmarkAdapter.Sequence, AlignOptions{})
+		}
+	}
+}
+
+func BenchmarkGlobalAlign(b *testing.B) {
+	reads := benchmarkReads(b)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, read := range reads {
+			read.Sequence.GlobalAlign(benchmarkAdapter.Sequence, AlignOptions{})
+		}
+	}
+}
+
+// referenceSGAlign is the semi-global aligner as it was before the inner loop was
+// rewritten, kept to benchmark against: it allocates full [][]int matrices for every
+// call, scores each cell through closures and fills it with maxima over freshly
+// allocated slices. It returns the score and the expanded CIGAR, which it built by
+// appending to a string; the alignment strings, built from there as they are now, are
+// left out. The gap matrix crossing a penalized edge, which the old aligner let start
+// from 0, can not be reached, as since the affine traceback was fixed (marked "fixed").
+func referenceSGAlign(q NucleotideSequence, s NucleotideSequence, ends FreeEnds, sc Scoring) (int, string) {
+
+	type movement struct {
+		score  int
+		origin int
+	}
+	maxMovement := func(list []movement) movement {
+		best := list[0]
+		for k := 1; k < len(list); k++ {
+			if list[k].score > best.score {
+				best = list[k]
+			}
+		}
+		return best
+	}
+
+	lenI, lenJ := len(s)+1, len(q)+1
+	h, g := sc.GapOpen, sc.GapExtend
+
+	H := make([][]int, lenJ)
+	I := make([][]int, lenJ)
+	J := make([][]int, lenJ)
+	D := make([][]int, lenJ)
+	for index := range H {
+		H[index] = make([]int, lenI)
+		J[index] = make([]int, lenI)
+		I[index] = make([]int, lenI)
+		D[index] = make([]int, lenI)
+	}
+
+	matcher := func(i int, j int) movement {
+		score, origin := sc.pair(s[i-1], q[j-1])
+		return movement{H[j-1][i-1] + score, origin}
+	}
+
+	for j := range H {
+		for i := range H[j] {
+			switch {
+			case i != 0 && j != 0:
+				I[j][i] = maxInt([]int{H[j][i-1] - h, I[j][i-1] - g})
+				J[j][i] = maxInt([]int{H[j-1][i] - h, J[j-1][i] - g})
+				best := maxMovement([]movement{matcher(i, j), {I[j][i], insI}, {J[j][i], insJ}})
+				H[j][i], D[j][i] = best.score, best.origin
+			case i == 0 && j == 0:
+				D[j][i] = gap
+			case i == 0 && ends.QueryStart, j == 0 && ends.SubjectStart:
+				D[j][i] = gap
+			case i == 0:
+				I[j][i] = negInf // fixed
+				J[j][i] = maxInt([]int{H[j-1][i] - h, J[j-1][i] - g})
+				best := maxMovement([]movement{{J[j][i], insJ}})
+				H[j][i], D[j][i] = best.score, best.origin
+			default:
+				I[j][i] = maxInt([]int{H[j][i-1] - h, I[j][i-1] - g})
+				J[j][i] = negInf // fixed
+				best := maxMovement([]movement{{I[j][i], insI}})
+				H[j][i], D[j][i] = best.score, best.origin
+			}
+		}
+	}
+
+	maxScore, maxPosition := H[lenJ-1][lenI-1], matrixPosition{i: lenI - 1, j: lenJ - 1}
+	if ends.SubjectEnd {
+		for i := 0; i < lenI; i++ {
+			if H[lenJ-1][i] > maxScore {
+				maxScore, maxPosition = H[lenJ-1][i], matrixPosition{i: i, j: lenJ - 1}
+			}
+		}
+	}
+	if ends.QueryEnd {
+		for j := 0; j < lenJ; j++ {
+			if H[j][lenI-1] > maxScore {
+				maxScore, maxPosition = H[j][lenI-1], matrixPosition{i: lenI - 1, j: j}
+			}
+		}
+	}
+
+	var revCIGAR []int
+	for p := maxPosition; D[p.j][p.i] != gap; {
+		revCIGAR = append(revCIGAR, D[p.j][p.i])
+		switch D[p.j][p.i] {
+		case insI:
+			p.i--
+		case insJ:
+			p.j--
+		default:
+			p.i, p.j = p.i-1, p.j-1
+		}
+	}
+
+	var CIGAR string
+	for k := range revCIGAR {
+		CIGAR += string(movementLetter(uint8(revCIGAR[len(revCIGAR)-1-k])))
+	}
+	return maxScore, CIGAR
+}
+
+// func (q NucleotideSequence) SG3pAlign(s NucleotideSequence, opts ...AlignOptions) PairWiseAlignment
+func TestReferenceSGAlign(t *testing.T) {
+	fmt.Println("testing the rewritten inner loop against the old semi-global aligner...")
+
+	file, err := os.Open("sample_50.fastq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	scanner := NewFASTQScanner(file)
+	for i := 0; ; i++ {
+		read, err := scanner.NextRead()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		for _, ends := range []FreeEnds{ThreePrimeEnds, FivePrimeEnds} {
+			a := read.Sequence.SGAlign(benchmarkAdapter.Sequence, AlignOptions{Ends: ends})
+			if score, _ := referenceSGAlign(read.Sequence, benchmarkAdapter.Sequence, ends, DefaultScoring); score != a.Score {
+				t.Errorf("read %d, %+v: expected a score of %d, but got %d", i, ends, score, a.Score)
+			}
+		}
+	}
+}
+
+// BenchmarkReferenceSG3pAlign measures the old aligner on the same reads as
+// BenchmarkSG3pAlign
+func BenchmarkReferenceSG3pAlign(b *testing.B) {
+	reads := benchmarkReads(b)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, read := range reads {
+			referenceSGAlign(read.Sequence, benchmarkAdapter.Sequence, ThreePrimeEnds, DefaultScoring)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package gobioinfo
+
+/*
+Linear memory alignment of long sequences.
+
+align keeps four full matrices of (len(subject)+1) x (len(query)+1) cells, which is
+fine for adapters and reads but not for a 10kb read against a 100kb reference. Above
+linearMemoryCells, align hands over to alignLinear, which gives exactly the same
+PairWiseAlignment (including the choice between equally scoring alignments) while only
+keeping a few rows and columns of the matrices at a time.
+
+The traceback follows the movements in D from the end of the alignment back to its start,
+through H and, inside a gap, the gap matrix the gap was scored in. Rather than storing D,
+alignLinear splits the rows of the matrix in half and, while filling the lower half,
+carries along for every cell and each of the three matrices the place (and matrix) where
+its traceback first leaves the lower half. Reading this off at the end cell gives the cell in the middle row
+that the alignment passes through, which splits the traceback into two smaller problems:
+the lower rows to the right of that cell and the upper rows to its left. These are solved
+in the same way until they are small enough to trace through directly. Like Hirschberg's
+algorithm, this roughly doubles the work of filling the matrix once, and memory grows
+with the sum of the sequence lengths (times the log of the query length for the nested
+halves) rather than with their product.
+*/
+
+// linearMemoryCells is the size of alignment matrix, in cells, above which align uses
+// the linear memory algorithm
+var linearMemoryCells = 1 << 22
+
+// linearBaseCells is the size of block which alignLinear traces back through directly
+var linearBaseCells = 1 << 14
+
+// alignLinear gives the same result as align, without keeping the whole matrices in memory
+func (a *aligner) alignLinear() PairWiseAlignment {
+
+	// fill the matrices once to find where the alignment ends
+	a.edges()
+	maxPosition, maxScore := a.findEnd(nil)
+
+	// trace back through the body of the matrices, and then along the first row or
+	// column if the alignment reaches them. An end outside of a band, where a global
+	// alignment can not reach the corner, gives no alignment.
+	position, state := maxPosition, inH
+	stopped := a.outside(position.i, position.j)
+	if !stopped && position.i > 0 && position.j > 0 {
+		position, state, stopped = a.trace(1, 1, position.j, position.i, inH, a.rowH, a.rowJ, a.colH[1:], a.colI[1:])
+	}
+	for !stopped {
+		d := a.colD[position.j]
+		if position.j == 0 {
+			d = a.rowD[position.i]
+		}
+		move, next, nextState, start := a.traceStep(position, state, d)
+		if start {
+			break
+		}
+		if move != 0 {
+			a.revCIGAR = append(a.revCIGAR, int(move))
+			a.last = position
+		}
+		position, state = next, nextState
+	}
+
+	if len(a.revCIGAR) == 0 {
+		return a.alignment(nil, maxPosition, maxScore)
+	}
+	return a.alignment(a.revCIGAR, a.last, maxScore)
+}
+
+// trace follows the traceback from the cell (r1, c1) of the matrix state through rows r0
+// to r1 and columns c0 to c1, whose edges are given as for fill, adding the movements to
+// revCIGAR. It returns the first cell reached outside of the block and the matrix the
+// traceback is in there, or the cell where the alignment starts and true.
+func (a *aligner) trace(r0 int, c0 int, r1 int, c1 int, state traceState, topH []int, topJ []int, leftH []int, leftI []int) (matrixPosition, traceState, bool) {
+
+	rows := r1 - r0 + 1
+	width := c1 - c0 + 1
+
+	// small blocks are filled in full and traced through directly
+	if rows == 1 || rows*width <= linearBaseCells {
+		D := make([]uint8, rows*width)
+		a.fill(r0, c0, r1, c1, topH, topJ, leftH, leftI, func(j int, H []int, I []int, J []int, row []uint8) {
+			copy(D[(j-r0)*width:(j-r0+1)*width], row[1:])
+		})
+
+		position := matrixPosition{i: c1, j: r1}
+		for position.j >= r0 && position.i >= c0 {
+			move, next, nextState, start := a.traceStep(position, state, D[(position.j-r0)*width+position.i-c0])
+			if start {
+				return position, state, true
+			}
+			if move != 0 {
+				a.revCIGAR = append(a.revCIGAR, int(move))
+				a.last = position
+			}
+			position, state = next, nextState
+		}
+		return position, state, false
+	}
+
+	// otherwise fill the whole block, keeping the middle row, and for each cell below it
+	// and each of the matrices the traceback can be in there, the cell and matrix where
+	// the traceback leaves the lower rows: a cell in the middle row, a cell in column
+	// c0-1, or the cell where the alignment starts
+	mid := (r0 + r1) / 2
+
+	midH := make([]int, width+1)
+	midJ := make([]int, width+1)
+
+	type exit struct {
+		position matrixPosition
+		state    traceState
+		stopped  bool
+	}
+	var prev, cur [3][]exit
+	for m := range prev {
+		prev[m], cur[m] = make([]exit, width+1), make([]exit, width+1)
+	}
+
+	a.fill(r0, c0, r1, c1, topH, topJ, leftH, leftI, func(j int, H []int, I []int, J []int, D []uint8) {
+		if j == mid {
+			copy(midH, H)
+			copy(midJ, J)
+		}
+		if j <= mid {
+			return
+		}
+		for k := 1; k <= width; k++ {
+			i := c0 + k - 1
+
+			// a gap goes on to the cell before in the gap matrix if it is extended, and in
+			// H if it was opened there
+			left := inH
+			if D[k]&extendI != 0 {
+				left = inI
+			}
+			if k == 1 {
+				cur[inI][k] = exit{matrixPosition{i: i - 1, j: j}, left, false}
+			} else {
+				cur[inI][k] = cur[left][k-1]
+			}
+
+			up := inH
+			if D[k]&extendJ != 0 {
+				up = inJ
+			}
+			if j-1 == mid {
+				cur[inJ][k] = exit{matrixPosition{i: i, j: j - 1}, up, false}
+			} else {
+				cur[inJ][k] = prev[up][k]
+			}
+
+			switch D[k] & moveBits {
+			case gap:
+				cur[inH][k] = exit{matrixPosition{i: i, j: j}, inH, true}
+			case insI:
+				cur[inH][k] = cur[inI][k]
+			case insJ:
+				cur[inH][k] = cur[inJ][k]
+			default:
+				if j-1 == mid || k == 1 {
+					cur[inH][k] = exit{matrixPosition{i: i - 1, j: j - 1}, inH, false}
+				} else {
+					cur[inH][k] = prev[inH][k-1]
+				}
+			}
+		}
+		prev, cur = cur, prev
+	})
+	end := prev[state][width]
+
+	// the traceback through the lower rows stays to the right of where it leaves them
+	left := c0
+	if end.position.i > c0 {
+		left = end.position.i
+	}
+	lowerH, lowerI := leftH[mid+1-r0:], leftI[mid+1-r0:]
+	if left > c0 {
+		lowerH, lowerI = make([]int, r1-mid), make([]int, r1-mid)
+		a.fill(mid+1, c0, r1, left-1, midH, midJ, leftH[mid+1-r0:], leftI[mid+1-r0:], func(j int, H []int, I []int, J []int, D []uint8) {
+			lowerH[j-mid-1] = H[left-c0]
+			lowerI[j-mid-1] = I[left-c0]
+		})
+	}
+
+	position, state, stopped := a.trace(mid+1, left, r1, c1, state, midH[left-c0:], midJ[left-c0:], lowerH, lowerI)
+	if stopped || position.j > mid || position.i < c0 {
+		return position, state, stopped
+	}
+
+	// carry on through the upper rows from where the traceback entered the middle row
+	return a.trace(r0, c0, mid, position.i, state, topH, topJ, leftH, leftI)
+}
@@ -0,0 +1,121 @@
+package gobioinfo
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// randomSequence returns n random bases, with the occasional N
+func randomSequence(r *rand.Rand, n int) NucleotideSequence {
+	bases := []rune("ACGTACGTACGTACGTN")
+	seq := make(NucleotideSequence, n)
+	for i := range seq {
+		seq[i] = bases[r.Intn(len(bases))]
+	}
+	return seq
+}
+
+// mutateSequence returns a copy of seq with about rate substitutions, insertions and
+// deletions per base
+func mutateSequence(r *rand.Rand, seq NucleotideSequence, rate float64) NucleotideSequence {
+	var mutated NucleotideSequence
+	for _, base := range seq {
+		switch x := r.Float64(); {
+		case x < rate/3:
+			mutated = append(mutated, randomSequence(r, 1)...)
+		case x < 2*rate/3:
+			mutated = append(mutated, base, randomSequence(r, 1)[0])
+		case x < rate:
+		default:
+			mutated = append(mutated, base)
+		}
+	}
+	return mutated
+}
+
+// func (a *aligner) alignLinear() PairWiseAlignment
+func TestAlignLinear(t *testing.T) {
+	fmt.Println("testing alignLinear()...")
+
+	// trace through tiny blocks so that the splitting is exercised on short sequences
+	defer func(cells int) { linearBaseCells = cells }(linearBaseCells)
+	linearBaseCells = 8
+
+	r := rand.New(rand.NewSource(1))
+
+	type testCase struct {
+		mode  AlignMode
+		ends  FreeEnds
+		sc    Scoring
+		query NucleotideSequence
+		subj  NucleotideSequence
+	}
+
+	var testSuite []testCase
+	endsList := []FreeEnds{AllEndsFree, ThreePrimeEnds, FivePrimeEnds, {}, {QueryStart: true, SubjectEnd: true}, {SubjectStart: true, QueryEnd: true}}
+	scorings := []Scoring{DefaultScoring, UnitScoring, NUC44Scoring}
+
+	for n := 0; n < 40; n++ {
+		subject := randomSequence(r, 20+r.Intn(120))
+		var query NucleotideSequence
+		switch n % 4 {
+		case 0:
+			query = randomSequence(r, 10+r.Intn(60))
+		case 1:
+			query = mutateSequence(r, subject, 0.1)
+		default:
+			from := r.Intn(len(subject))
+			to := from + r.Intn(len(subject)-from)
+			query = append(randomSequence(r, r.Intn(15)), mutateSequence(r, subject[from:to], 0.15)...)
+			query = append(query, randomSequence(r, r.Intn(15))...)
+		}
+		if len(query) == 0 {
+			query = randomSequence(r, 5)
+		}
+		sc := scorings[n%len(scorings)]
+		for _, ends := range endsList {
+			testSuite = append(testSuite, testCase{SemiGlobal, ends, sc, query, subject})
+		}
+		testSuite = append(testSuite, testCase{Global, FreeEnds{}, sc, query, subject})
+		testSuite = append(testSuite, testCase{Local, FreeEnds{}, sc, query, subject})
+	}
+
+	for _, elem := range testSuite {
+		expected := elem.query.align(elem.subj, elem.mode, elem.ends, elem.sc, TieBreak{}, nil)
+		result := newAligner(elem.query, elem.subj, elem.mode, elem.ends, elem.sc, TieBreak{}, nil).alignLinear()
+		if result.ExpandedCIGAR != expected.ExpandedCIGAR ||
+			result.SubjectStart != expected.SubjectStart ||
+			result.QueryStart != expected.QueryStart ||
+			result.GappedQuery != expected.GappedQuery {
+			t.Errorf("mode %d, ends %+v, query %s, subject %s:\nexpected %q q%d s%d\nbut got  %q q%d s%d",
+				elem.mode, elem.ends, string(elem.query), string(elem.subj),
+				expected.ExpandedCIGAR, expected.QueryStart, expected.SubjectStart,
+				result.ExpandedCIGAR, result.QueryStart, result.SubjectStart)
+		}
+
+		// and the same within a band
+		band := &Band{Width: 5, Offset: r.Intn(21) - 10}
+		expected = elem.query.align(elem.subj, elem.mode, elem.ends, elem.sc, TieBreak{}, band)
+		result = newAligner(elem.query, elem.subj, elem.mode, elem.ends, elem.sc, TieBreak{}, band).alignLinear()
+		if result.ExpandedCIGAR != expected.ExpandedCIGAR ||
+			result.SubjectStart != expected.SubjectStart ||
+			result.QueryStart != expected.QueryStart {
+			t.Errorf("band %+v, mode %d, ends %+v, query %s, subject %s:\nexpected %q q%d s%d\nbut got  %q q%d s%d",
+				*band, elem.mode, elem.ends, string(elem.query), string(elem.subj),
+				expected.ExpandedCIGAR, expected.QueryStart, expected.SubjectStart,
+				result.ExpandedCIGAR, result.QueryStart, result.SubjectStart)
+		}
+	}
+
+	// align switches over on its own for large matrices
+	defer func(cells int) { linearMemoryCells = cells }(linearMemoryCells)
+	query := NucleotideSequence("GTGTCAGCACA")
+	subject := NucleotideSequence("CACATACACTAAGAAGGTCCTGGACGCGTGTCAGC")
+	expected := query.SG3pAlign(subject)
+	linearMemoryCells = 10
+	if result := query.SG3pAlign(subject); result.ExpandedCIGAR != expected.ExpandedCIGAR || result.SubjectStart != expected.SubjectStart {
+		t.Errorf("expected %q at %d above the size threshold, but got %q at %d",
+			expected.ExpandedCIGAR, expected.SubjectStart, result.ExpandedCIGAR, result.SubjectStart)
+	}
+}
@@ -0,0 +1,299 @@
+package gobioinfo
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/*
+Inspecting the alignment matrices.
+
+AlignMatrices fills the same matrices as Align, keeps all of them and traces the
+alignment back through them, so that the choice of an alignment can be followed cell by
+cell. The matrices can be written as text grids for reading in a terminal, as a CSV
+(one line per cell) for loading into R or pandas, or as an SVG heatmap of H with the
+traceback drawn over it.
+
+The whole of each matrix is held in memory, so this is meant for short sequences such as
+an adapter against a read.
+*/
+
+// AlignmentMatrices holds the filled alignment matrices of a query and subject and the
+// alignment traced back through them. Each matrix is indexed [j][i], by the position along
+// the query and then the subject, with row and column 0 before the first base.
+type AlignmentMatrices struct {
+	Query     NucleotideSequence
+	Subject   NucleotideSequence
+	Alignment PairWiseAlignment
+	// H holds the score of the best alignment ending at each cell, and I and J the
+	// scores of the best ending in a subject base against a gap ("i") and a query base
+	// against a gap ("j"). A cell which can not be reached holds a large negative value,
+	// written as -inf.
+	H, I, J [][]int
+	// Traceback holds the movement chosen for each cell, as the letter of the
+	// ExpandedCIGAR, or '-' where an alignment may start
+	Traceback [][]byte
+
+	// path holds the cells of the alignment, from the cell before its first column to its end
+	path []matrixPosition
+}
+
+// AlignMatrices aligns the query to the subject as Align does, keeping the matrices. Only
+// the subject as given is aligned to, even if BothStrands is set.
+func (q NucleotideSequence) AlignMatrices(s NucleotideSequence, opts ...AlignOptions) AlignmentMatrices {
+
+	o := firstOptions(opts)
+	o.BothStrands = false
+
+	a := newAligner(q, s, o.Mode, o.ends(), o.scoring(), o.Ties, nil)
+	H, I, J, D := a.matrices()
+
+	lenI := len(s) + 1
+	lenJ := len(q) + 1
+
+	m := AlignmentMatrices{
+		Query:     q,
+		Subject:   s,
+		Alignment: q.Align(s, o),
+		H:         make([][]int, lenJ),
+		I:         make([][]int, lenJ),
+		J:         make([][]int, lenJ),
+		Traceback: make([][]byte, lenJ),
+	}
+	for j := 0; j < lenJ; j++ {
+		m.H[j], m.I[j], m.J[j] = H[j*lenI:(j+1)*lenI], I[j*lenI:(j+1)*lenI], J[j*lenI:(j+1)*lenI]
+		m.Traceback[j] = make([]byte, lenI)
+		for i := range m.Traceback[j] {
+			m.Traceback[j][i] = movementLetter(D[j*lenI+i] & moveBits)
+		}
+	}
+
+	if m.Alignment.ExpandedCIGAR != "" {
+		p := matrixPosition{i: m.Alignment.SubjectStart, j: m.Alignment.QueryStart}
+		m.path = append(m.path, p)
+		for _, c := range m.Alignment.ExpandedCIGAR {
+			switch c {
+			case 'i':
+				p.i++
+			case 'j':
+				p.j++
+			default:
+				p.i++
+				p.j++
+			}
+			m.path = append(m.path, p)
+		}
+	}
+
+	return m
+}
+
+// movementLetter returns the letter of a movement, as in an ExpandedCIGAR
+func movementLetter(d uint8) byte {
+	switch d {
+	case match:
+		return 'm'
+	case mismatch:
+		return 'x'
+	case neutral:
+		return 'n'
+	case insI:
+		return 'i'
+	case insJ:
+		return 'j'
+	}
+	return '-'
+}
+
+// movementArrow returns the direction of a movement letter back through the matrices
+func movementArrow(letter byte) string {
+	switch letter {
+	case 'm', 'x', 'n':
+		return "\\"
+	case 'i':
+		return "<"
+	case 'j':
+		return "^"
+	}
+	return "."
+}
+
+// reachable reports whether a matrix value is a score rather than minus infinity
+func reachable(value int) bool {
+	return value > negInf/2
+}
+
+// scoreString formats a matrix value, or -inf if the cell can not be reached
+func scoreString(value int) string {
+	if !reachable(value) {
+		return "-inf"
+	}
+	return strconv.Itoa(value)
+}
+
+// onPath returns whether each cell lies on the traceback of the alignment, indexed [j][i]
+func (m AlignmentMatrices) onPath() [][]bool {
+	on := make([][]bool, len(m.Query)+1)
+	for j := range on {
+		on[j] = make([]bool, len(m.Subject)+1)
+	}
+	for _, p := range m.path {
+		on[p.j][p.i] = true
+	}
+	return on
+}
+
+// matrixBase returns the base before row or column k of the matrices, or "-" for row or column 0
+func matrixBase(seq NucleotideSequence, k int) string {
+	if k == 0 {
+		return "-"
+	}
+	return string(seq[k-1])
+}
+
+// WriteText writes H, I, J and the traceback as grids with the subject across the top
+// and the query down the side. Cells on the traceback of the alignment are marked with a
+// "*", and the traceback grid points each cell back to the one it came from: "\" along
+// the diagonal, "<" to the left, "^" up, and "." where an alignment may start.
+func (m AlignmentMatrices) WriteText(w io.Writer) error {
+
+	on := m.onPath()
+	var buf bytes.Buffer
+
+	grid := func(title string, cell func(i int, j int) string) {
+		width := 2
+		for j := range m.Traceback {
+			for i := range m.Traceback[j] {
+				if n := len(cell(i, j)) + 1; n > width {
+					width = n
+				}
+			}
+		}
+
+		buf.WriteString(title + "\n")
+		fmt.Fprintf(&buf, "%2s", "")
+		for i := range m.Traceback[0] {
+			fmt.Fprintf(&buf, " %*s", width, matrixBase(m.Subject, i))
+		}
+		buf.WriteString("\n")
+		for j := range m.Traceback {
+			fmt.Fprintf(&buf, "%2s", matrixBase(m.Query, j))
+			for i := range m.Traceback[j] {
+				mark := ""
+				if on[j][i] {
+					mark = "*"
+				}
+				fmt.Fprintf(&buf, " %*s", width, mark+cell(i, j))
+			}
+			buf.WriteString("\n")
+		}
+		buf.WriteString("\n")
+	}
+
+	grid("H", func(i int, j int) string { return scoreString(m.H[j][i]) })
+	grid("I", func(i int, j int) string { return scoreString(m.I[j][i]) })
+	grid("J", func(i int, j int) string { return scoreString(m.J[j][i]) })
+	grid("traceback", func(i int, j int) string { return movementArrow(m.Traceback[j][i]) })
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// WriteCSV writes the matrices as comma separated values, one line per cell under a
+// header line, with -Inf for cells which can not be reached
+func (m AlignmentMatrices) WriteCSV(w io.Writer) error {
+
+	on := m.onPath()
+	value := func(v int) string {
+		if !reachable(v) {
+			return "-Inf"
+		}
+		return strconv.Itoa(v)
+	}
+
+	c := csv.NewWriter(w)
+	c.Write([]string{"query_position", "subject_position", "query_base", "subject_base", "H", "I", "J", "traceback", "on_path"})
+	for j := range m.Traceback {
+		for i := range m.Traceback[j] {
+			c.Write([]string{
+				strconv.Itoa(j), strconv.Itoa(i), matrixBase(m.Query, j), matrixBase(m.Subject, i),
+				value(m.H[j][i]), value(m.I[j][i]), value(m.J[j][i]),
+				string(m.Traceback[j][i]), strconv.FormatBool(on[j][i]),
+			})
+		}
+	}
+	c.Flush()
+	return c.Error()
+}
+
+// size in pixels of each cell, and of the margin for the bases, of the SVG heatmap
+const svgCell = 28
+
+// WriteSVG writes H as an SVG heatmap, shading each cell from white (the lowest score) to
+// blue (the highest) with cells which can not be reached in grey, and draws the traceback
+// of the alignment over it in red
+func (m AlignmentMatrices) WriteSVG(w io.Writer) error {
+
+	lenI := len(m.Subject) + 1
+	lenJ := len(m.Query) + 1
+
+	lowest, highest := 0, 0
+	for j := range m.H {
+		for _, v := range m.H[j] {
+			if !reachable(v) {
+				continue
+			}
+			if v < lowest {
+				lowest = v
+			}
+			if v > highest {
+				highest = v
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	width, height := (lenI+1)*svgCell, (lenJ+1)*svgCell
+	fmt.Fprintf(&buf, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\" font-family=\"monospace\" font-size=\"10\" text-anchor=\"middle\" dominant-baseline=\"central\">\n", width, height, width, height)
+	fmt.Fprintf(&buf, "<title>H: score %d, %s</title>\n", m.Alignment.Score, m.Alignment.CIGAR())
+
+	// the bases along the top and down the side
+	for i := 0; i < lenI; i++ {
+		fmt.Fprintf(&buf, "<text x=\"%d\" y=\"%d\">%s</text>\n", (i+1)*svgCell+svgCell/2, svgCell/2, html.EscapeString(matrixBase(m.Subject, i)))
+	}
+	for j := 0; j < lenJ; j++ {
+		fmt.Fprintf(&buf, "<text x=\"%d\" y=\"%d\">%s</text>\n", svgCell/2, (j+1)*svgCell+svgCell/2, html.EscapeString(matrixBase(m.Query, j)))
+	}
+
+	for j := 0; j < lenJ; j++ {
+		for i := 0; i < lenI; i++ {
+			x, y := (i+1)*svgCell, (j+1)*svgCell
+			v := m.H[j][i]
+			fill := "#dddddd"
+			if reachable(v) {
+				shade := ratio(v-lowest, highest-lowest, 1)
+				fill = fmt.Sprintf("#%02x%02x%02x", 255-int(shade*(255-33)), 255-int(shade*(255-102)), 255-int(shade*(255-172)))
+			}
+			fmt.Fprintf(&buf, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\" stroke=\"white\"/>\n", x, y, svgCell, svgCell, fill)
+			fmt.Fprintf(&buf, "<text x=\"%d\" y=\"%d\">%s</text>\n", x+svgCell/2, y+svgCell/2, scoreString(v))
+		}
+	}
+
+	if len(m.path) > 0 {
+		points := make([]string, len(m.path))
+		for k, p := range m.path {
+			points[k] = fmt.Sprintf("%d,%d", (p.i+1)*svgCell+svgCell/2, (p.j+1)*svgCell+svgCell/2)
+		}
+		fmt.Fprintf(&buf, "<polyline points=\"%s\" fill=\"none\" stroke=\"red\" stroke-width=\"2\" stroke-opacity=\"0.7\"/>\n", strings.Join(points, " "))
+	}
+
+	buf.WriteString("</svg>\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
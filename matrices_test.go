@@ -0,0 +1,103 @@
+package gobioinfo
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// func (q NucleotideSequence) AlignMatrices(s NucleotideSequence, opts ...AlignOptions) AlignmentMatrices
+func TestAlignMatrices(t *testing.T) {
+	fmt.Println("testing AlignMatrices()...")
+
+	testSuite := []struct {
+		query   string
+		subject string
+		opts    AlignOptions
+	}{
+		{"GATNCA", "CCGATGCAT", AlignOptions{}},
+		{"GAT", "CCGATG", AlignOptions{Mode: Global}},
+		{"GATTACA", "CCGATACACC", AlignOptions{Mode: Local}},
+		{"AAAA", "CCCC", AlignOptions{Mode: Local}},
+	}
+
+	for i, elem := range testSuite {
+		m := NucleotideSequence(elem.query).AlignMatrices(NucleotideSequence(elem.subject), elem.opts)
+		if expected := NucleotideSequence(elem.query).Align(NucleotideSequence(elem.subject), elem.opts); m.Alignment.ExpandedCIGAR != expected.ExpandedCIGAR {
+			t.Errorf("test %d: expected the alignment %s, but got %s", i, expected.ExpandedCIGAR, m.Alignment.ExpandedCIGAR)
+		}
+		if len(m.H) != len(elem.query)+1 || len(m.H[0]) != len(elem.subject)+1 {
+			t.Fatalf("test %d: expected %dx%d matrices, but got %dx%d", i, len(elem.query)+1, len(elem.subject)+1, len(m.H), len(m.H[0]))
+		}
+
+		// the traceback along the path spells out the alignment, ending at its score
+		var cigar []byte
+		for k, p := range m.path {
+			if k > 0 {
+				cigar = append(cigar, m.Traceback[p.j][p.i])
+			}
+		}
+		if string(cigar) != m.Alignment.ExpandedCIGAR {
+			t.Errorf("test %d: expected the traceback %s along the path, but got %s", i, m.Alignment.ExpandedCIGAR, cigar)
+		}
+		if len(m.path) > 0 {
+			if end := m.path[len(m.path)-1]; m.H[end.j][end.i] != m.Alignment.Score {
+				t.Errorf("test %d: expected H to be %d at the end of the path, but got %d", i, m.Alignment.Score, m.H[end.j][end.i])
+			}
+		}
+	}
+}
+
+// func (m AlignmentMatrices) WriteText(w io.Writer) error
+func TestAlignmentMatricesWriters(t *testing.T) {
+	fmt.Println("testing AlignmentMatrices.WriteText(), WriteCSV() and WriteSVG()...")
+
+	m := NucleotideSequence("GAT").AlignMatrices(NucleotideSequence("CCGATG"), AlignOptions{Mode: Global})
+
+	var text bytes.Buffer
+	if err := m.WriteText(&text); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	expected := "" +
+		"H\n" +
+		"      -    C    C    G    A    T    G\n" +
+		" -   *0  *-6  *-9  -12  -15  -18  -21\n" +
+		" G   -6   -4  -10  *-6  -12  -15  -15\n" +
+		" A   -9  -10   -8  -12  *-3   -9  -12\n" +
+		" T  -12  -13  -14  -12   -9   *0  *-6\n\n"
+	if !strings.HasPrefix(text.String(), expected) {
+		t.Errorf("expected the H grid\n%s\nbut got\n%s", expected, text.String())
+	}
+	if !strings.Contains(text.String(), " G  -inf   -12   -10  *-13") || !strings.Contains(text.String(), "traceback\n") {
+		t.Errorf("expected the I and traceback grids, but got\n%s", text.String())
+	}
+
+	var csv bytes.Buffer
+	if err := m.WriteCSV(&csv); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(csv.String(), "\n"), "\n")
+	if len(lines) != 1+4*7 {
+		t.Fatalf("expected a header and 28 lines, but got %d lines", len(lines))
+	}
+	if lines[1] != "0,0,-,-,0,-Inf,-Inf,-,true" || lines[17] != "2,2,A,C,-8,-16,-16,x,false" {
+		t.Errorf("unexpected lines %q and %q", lines[1], lines[17])
+	}
+
+	var svg bytes.Buffer
+	if err := m.WriteSVG(&svg); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if !strings.HasPrefix(svg.String(), "<svg ") || !strings.HasSuffix(svg.String(), "</svg>\n") ||
+		!strings.Contains(svg.String(), "<polyline points=\"42,42 70,42 98,42 126,70 154,98 182,126 210,126\"") {
+		t.Errorf("unexpected SVG %s", svg.String())
+	}
+
+	// characters of the sequences which are special in XML are escaped
+	svg.Reset()
+	NucleotideSequence("A<T").AlignMatrices(NucleotideSequence("A&T"), AlignOptions{Mode: Global}).WriteSVG(&svg)
+	if !strings.Contains(svg.String(), ">&lt;</text>") || !strings.Contains(svg.String(), ">&amp;</text>") || strings.Contains(svg.String(), "><</text>") {
+		t.Errorf("expected < and & in the sequences to be escaped, but got %s", svg.String())
+	}
+}
@@ -0,0 +1,272 @@
+package gobioinfo
+
+import (
+	"fmt"
+)
+
+/*
+Bit-parallel approximate matching of short queries, such as adapters and barcodes.
+
+An EditSearcher finds where a query of up to 64 bases occurs in a subject with at most
+k substitutions, insertions and deletions, using Myers' bit-vector algorithm (in the
+formulation of Hyyrö). Each column of the edit distance matrix is held as two 64 bit
+words of differences between neighbouring cells, and is advanced over a subject base in
+a dozen word operations, which is many times faster than filling the matrices in
+align.go. Bases are compared as by the aligner: case-insensitively, with IUPAC
+ambiguity codes (including N) matching any base they may stand for.
+
+A match records where it ends in the subject, as the algorithm finds it, and where it
+starts, which is found by running the algorithm backwards over the few bases before
+the end. EditMatch.Alignment turns a match into a PairWiseAlignment.
+*/
+
+// maxEditQuery is the longest query an EditSearcher can search for, one base for each
+// bit of a uint64
+const maxEditQuery = 64
+
+// EditMatch is an approximate occurrence of all or part of the query in a subject
+type EditMatch struct {
+	QueryStart   int // first query base of the match
+	QueryEnd     int // just after the last query base of the match
+	SubjectStart int // first subject base of the match
+	SubjectEnd   int // just after the last subject base of the match
+	Distance     int // number of substitutions, insertions and deletions
+}
+
+// Alignment aligns the matched part of the query to the matched part of the subject,
+// giving an alignment with Distance edits
+func (m EditMatch) Alignment(query NucleotideSequence, subject NucleotideSequence) PairWiseAlignment {
+
+	alignment := query[m.QueryStart:m.QueryEnd].GlobalAlign(subject[m.SubjectStart:m.SubjectEnd], AlignOptions{Scoring: EditScoring})
+
+	alignment.Query = query
+	alignment.Subject = subject
+	alignment.QueryStart += m.QueryStart
+	alignment.SubjectStart += m.SubjectStart
+
+	return alignmentRepr(alignment)
+}
+
+// EditSearcher searches subjects for approximate occurrences of a query of up to 64 bases
+type EditSearcher struct {
+	query NucleotideSequence
+	// for each ASCII subject character, bit k of forward is set if it matches query[k]
+	// and bit k of reverse is set if it matches query[len(query)-1-k]
+	forward [128]uint64
+	reverse [128]uint64
+}
+
+// NewEditSearcher takes a query of 1 to 64 bases and returns an EditSearcher for it
+func NewEditSearcher(query NucleotideSequence) (*EditSearcher, error) {
+
+	if len(query) == 0 || len(query) > maxEditQuery {
+		return nil, fmt.Errorf("an edit search query must be 1 to %d bases long, not %d", maxEditQuery, len(query))
+	}
+
+	e := &EditSearcher{query: query}
+	for c := range e.forward {
+		e.forward[c] = e.mask(rune(c), false)
+		e.reverse[c] = e.mask(rune(c), true)
+	}
+
+	return e, nil
+}
+
+// mask returns the bits of the query positions which subject base c matches, counting
+// from the end of the query if reversed is true
+func (e *EditSearcher) mask(c rune, reversed bool) uint64 {
+	var bits uint64
+	for k, base := range e.query {
+		if classifyPair(c, base) == mismatch {
+			continue
+		}
+		if reversed {
+			k = len(e.query) - 1 - k
+		}
+		bits |= 1 << uint(k)
+	}
+	return bits
+}
+
+// eq returns the match bits of subject base c
+func (e *EditSearcher) eq(c rune, reversed bool) uint64 {
+	if c < 0 || c >= 128 {
+		return e.mask(c, reversed)
+	}
+	if reversed {
+		return e.reverse[c]
+	}
+	return e.forward[c]
+}
+
+// editColumn is a column of the edit distance matrix between the query and the subject
+// bases seen so far, held as the differences between each cell and the one above it:
+// bit i of pv is set where the difference in row i+1 is +1, and of mv where it is -1
+type editColumn struct {
+	pv uint64
+	mv uint64
+}
+
+// newEditColumn returns the first column, where each cell is one more than the last
+func newEditColumn() editColumn {
+	return editColumn{pv: ^uint64(0)}
+}
+
+// advance moves the column on over a subject base with the match bits eq. hin is the
+// difference along the first row: 0 if a match may start at any subject base, and 1 if
+// it has to start at the first one. It returns the difference along the row of top.
+func (c *editColumn) advance(eq uint64, hin uint64, top uint64) int {
+	xv := eq | c.mv
+	xh := (((eq & c.pv) + c.pv) ^ c.pv) | eq
+	ph := c.mv | ^(xh | c.pv)
+	mh := c.pv & xh
+
+	hout := 0
+	switch {
+	case ph&top != 0:
+		hout = 1
+	case mh&top != 0:
+		hout = -1
+	}
+
+	ph = ph<<1 | hin
+	mh <<= 1
+	c.pv = mh | ^(xv | ph)
+	c.mv = ph & xv
+
+	return hout
+}
+
+// bestPrefix reads the distance of each prefix of the query from a column, and returns
+// the length of the prefix with the most bases left after taking off its edits, of those
+// within maxDistance
+func (c editColumn) bestPrefix(length int, maxDistance int) (int, int, bool) {
+	bestLength, bestDistance, bestScore := 0, 0, 0
+	distance := 0
+	for i := 1; i <= length; i++ {
+		bit := uint64(1) << uint(i-1)
+		if c.pv&bit != 0 {
+			distance++
+		} else if c.mv&bit != 0 {
+			distance--
+		}
+		if distance <= maxDistance && i-distance > bestScore {
+			bestLength, bestDistance, bestScore = i, distance, i-distance
+		}
+	}
+	return bestLength, bestDistance, bestLength > 0
+}
+
+// anchored finds the stretch of subject which is closest to part of the query, where the
+// stretch has to end at from (searching backwards for the prefix of the query of the given
+// length), or start at it (searching forwards for the suffix of that length). It returns
+// the other end of the stretch and its distance; ties go to the longer stretch.
+func (e *EditSearcher) anchored(s NucleotideSequence, from int, length int, backwards bool) (int, int) {
+
+	shift := uint(len(e.query) - length)
+	top := uint64(1) << uint(length-1)
+
+	column := newEditColumn()
+	distance, best, bestEnd := length, length, from
+
+	// a stretch more than best bases longer than the query can not do better
+	for n := 1; n <= length+best; n++ {
+		position := from + n - 1
+		if backwards {
+			position = from - n
+		}
+		if position < 0 || position >= len(s) {
+			break
+		}
+
+		distance += column.advance(e.eq(s[position], backwards)>>shift, 1, top)
+		if distance <= best {
+			best = distance
+			bestEnd = position + 1
+			if backwards {
+				bestEnd = position
+			}
+		}
+	}
+
+	return bestEnd, best
+}
+
+// Search returns every match of the whole query in the subject with at most maxDistance
+// edits, in order of where they end. Near an occurrence the query usually matches with
+// several ends, each of which is reported.
+func (e *EditSearcher) Search(s NucleotideSequence, maxDistance int) []EditMatch {
+
+	length := len(e.query)
+	top := uint64(1) << uint(length-1)
+
+	var matches []EditMatch
+
+	column := newEditColumn()
+	distance := length
+	for j, c := range s {
+		distance += column.advance(e.eq(c, false), 0, top)
+		if distance <= maxDistance {
+			start, _ := e.anchored(s, j+1, length, true)
+			matches = append(matches, EditMatch{
+				QueryEnd:     length,
+				SubjectStart: start,
+				SubjectEnd:   j + 1,
+				Distance:     distance,
+			})
+		}
+	}
+
+	return matches
+}
+
+// ThreePrimeMatch finds the best match of the start of the query at the end of the
+// subject, as for a 3' adapter which may run off the end of a read. The match is the
+// prefix of the query with the most bases left after taking off its edits, of those with
+// at most maxDistance edits; false is returned if there is none.
+func (e *EditSearcher) ThreePrimeMatch(s NucleotideSequence, maxDistance int) (EditMatch, bool) {
+
+	column := newEditColumn()
+	for _, c := range s {
+		column.advance(e.eq(c, false), 0, 0)
+	}
+
+	length, distance, ok := column.bestPrefix(len(e.query), maxDistance)
+	if !ok {
+		return EditMatch{}, false
+	}
+
+	start, _ := e.anchored(s, len(s), length, true)
+
+	return EditMatch{
+		QueryEnd:     length,
+		SubjectStart: start,
+		SubjectEnd:   len(s),
+		Distance:     distance,
+	}, true
+}
+
+// FivePrimeMatch finds the best match of the end of the query at the start of the
+// subject, as for a 5' adapter which may be cut short at the start of a read. The match is
+// chosen as for ThreePrimeMatch.
+func (e *EditSearcher) FivePrimeMatch(s NucleotideSequence, maxDistance int) (EditMatch, bool) {
+
+	column := newEditColumn()
+	for j := len(s) - 1; j >= 0; j-- {
+		column.advance(e.eq(s[j], true), 0, 0)
+	}
+
+	length, distance, ok := column.bestPrefix(len(e.query), maxDistance)
+	if !ok {
+		return EditMatch{}, false
+	}
+
+	end, _ := e.anchored(s, 0, length, false)
+
+	return EditMatch{
+		QueryStart: len(e.query) - length,
+		QueryEnd:   len(e.query),
+		SubjectEnd: end,
+		Distance:   distance,
+	}, true
+}
@@ -0,0 +1,200 @@
+package gobioinfo
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// editDistances returns, for each end in the subject, the edit distance between the query
+// and the closest stretch of subject ending there, filling the whole matrix
+func editDistances(q NucleotideSequence, s NucleotideSequence) []int {
+	previous := make([]int, len(s)+1)
+	current := make([]int, len(s)+1)
+	for i := 1; i <= len(q); i++ {
+		current[0] = i
+		for j := 1; j <= len(s); j++ {
+			cost := 1
+			if classifyPair(s[j-1], q[i-1]) != mismatch {
+				cost = 0
+			}
+			current[j] = minInt(previous[j-1]+cost, minInt(previous[j]+1, current[j-1]+1))
+		}
+		previous, current = current, previous
+	}
+	return previous
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// checkEditMatch checks that a match turns into an alignment with the right span and
+// number of edits
+func checkEditMatch(t *testing.T, query NucleotideSequence, subject NucleotideSequence, m EditMatch) {
+	a := m.Alignment(query, subject)
+	edits := strings.Count(a.ExpandedCIGAR, "x") + strings.Count(a.ExpandedCIGAR, "i") + strings.Count(a.ExpandedCIGAR, "j")
+	subjectEnd := a.SubjectStart + len(a.ExpandedCIGAR) - strings.Count(a.ExpandedCIGAR, "j")
+	queryEnd := a.QueryStart + len(a.ExpandedCIGAR) - strings.Count(a.ExpandedCIGAR, "i")
+	if edits != m.Distance || a.SubjectStart != m.SubjectStart || subjectEnd != m.SubjectEnd ||
+		a.QueryStart != m.QueryStart || queryEnd != m.QueryEnd {
+		t.Errorf("match %+v of %s in %s gave the alignment %q at q%d s%d",
+			m, string(query), string(subject), a.ExpandedCIGAR, a.QueryStart, a.SubjectStart)
+	}
+}
+
+// func NewEditSearcher(query NucleotideSequence) (*EditSearcher, error)
+func TestNewEditSearcher(t *testing.T) {
+	fmt.Println("testing NewEditSearcher()...")
+
+	for _, length := range []int{0, 65} {
+		if _, err := NewEditSearcher(NucleotideSequence(strings.Repeat("A", length))); err == nil {
+			t.Errorf("expected an error for a query of %d bases", length)
+		}
+	}
+	if _, err := NewEditSearcher(NucleotideSequence(strings.Repeat("A", 64))); err != nil {
+		t.Error("unexpected error for a query of 64 bases: ", err)
+	}
+}
+
+// func (e *EditSearcher) Search(s NucleotideSequence, maxDistance int) []EditMatch
+func TestEditSearcherSearch(t *testing.T) {
+	fmt.Println("testing EditSearcher.Search()...")
+
+	query := NucleotideSequence("GTGTCAGTCAC")
+	searcher, _ := NewEditSearcher(query)
+
+	// an exact occurrence, and one with a base missing
+	subject := NucleotideSequence("CACAGGGAGGACGATGTGTCAGTCACTTCCAGGTGTCAGCACA")
+
+	expected := []EditMatch{
+		{0, 11, 15, 25, 1},
+		{0, 11, 15, 26, 0},
+		{0, 11, 15, 27, 1},
+		{0, 11, 32, 42, 1},
+		{0, 11, 32, 43, 2},
+	}
+
+	matches := searcher.Search(subject, 2)
+	if len(matches) < len(expected) {
+		t.Fatalf("expected at least %d matches, but got %+v", len(expected), matches)
+	}
+	for _, m := range expected {
+		found := false
+		for _, result := range matches {
+			if result == m {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected the match %+v, but got %+v", m, matches)
+		}
+	}
+
+	// the distances are those of the full dynamic programming matrix, with IUPAC codes
+	r := rand.New(rand.NewSource(3))
+	for n := 0; n < 200; n++ {
+		query := randomSequence(r, 1+r.Intn(64))
+		subject := mutateSequence(r, append(randomSequence(r, r.Intn(40)), query...), 0.15)
+		subject = append(subject, randomSequence(r, r.Intn(40))...)
+		maxDistance := r.Intn(len(query)/3 + 1)
+
+		searcher, _ := NewEditSearcher(query)
+		matches := searcher.Search(subject, maxDistance)
+		distances := editDistances(query, subject)
+
+		var ends []int
+		for end, distance := range distances[1:] {
+			if distance <= maxDistance {
+				ends = append(ends, end+1)
+			}
+		}
+		if len(ends) != len(matches) {
+			t.Errorf("query %s, subject %s: expected matches ending at %v, but got %+v", string(query), string(subject), ends, matches)
+			continue
+		}
+		for k, m := range matches {
+			if m.SubjectEnd != ends[k] || m.Distance != distances[m.SubjectEnd] {
+				t.Errorf("query %s, subject %s: expected a distance of %d at %d, but got %+v",
+					string(query), string(subject), distances[ends[k]], ends[k], m)
+			}
+			checkEditMatch(t, query, subject, m)
+		}
+	}
+}
+
+// func (e *EditSearcher) ThreePrimeMatch(s NucleotideSequence, maxDistance int) (EditMatch, bool)
+// func (e *EditSearcher) FivePrimeMatch(s NucleotideSequence, maxDistance int) (EditMatch, bool)
+func TestEditSearcherAnchoredMatch(t *testing.T) {
+	fmt.Println("testing EditSearcher.ThreePrimeMatch() and FivePrimeMatch()...")
+
+	query := NucleotideSequence("AGATCGGAAGAGC")
+	searcher, _ := NewEditSearcher(query)
+
+	type testPair struct {
+		subject     string
+		maxDistance int
+		threePrime  bool
+		found       bool
+		match       EditMatch
+	}
+
+	testSuite := []testPair{
+		// a whole adapter, and one running off the end of the read
+		{"TTGACCAGTAAGATCGGAAGAGC", 0, true, true, EditMatch{0, 13, 10, 23, 0}},
+		{"TTGACCAGTAAGATCGGA", 0, true, true, EditMatch{0, 8, 10, 18, 0}},
+		// with a substitution and a deletion
+		{"TTGACCAGTAAGTTCGGA", 1, true, true, EditMatch{0, 8, 10, 18, 1}},
+		{"TTGACCAGTAAGATGGAAG", 1, true, true, EditMatch{0, 10, 10, 19, 1}},
+		// without any edits allowed only the last base matches
+		{"TTGACCAGTAAGTTCGGA", 0, true, true, EditMatch{0, 1, 17, 18, 0}},
+		{"CCCC", 0, true, false, EditMatch{}},
+		// the end of a 5' adapter at the start of a read
+		{"GAAGAGCTTGACCAGTA", 0, false, true, EditMatch{6, 13, 0, 7, 0}},
+		{"GAAGTGCTTGACCAGTA", 1, false, true, EditMatch{6, 13, 0, 7, 1}},
+		{"TTTT", 0, false, false, EditMatch{}},
+	}
+
+	for i, elem := range testSuite {
+		subject := NucleotideSequence(elem.subject)
+		var result EditMatch
+		var found bool
+		if elem.threePrime {
+			result, found = searcher.ThreePrimeMatch(subject, elem.maxDistance)
+		} else {
+			result, found = searcher.FivePrimeMatch(subject, elem.maxDistance)
+		}
+		if found != elem.found || result != elem.match {
+			t.Errorf("test %d: expected %+v (%v), but got %+v (%v)", i, elem.match, elem.found, result, found)
+		}
+		if found {
+			checkEditMatch(t, query, subject, result)
+		}
+	}
+}
+
+func BenchmarkEditSearcherThreePrimeMatch(b *testing.B) {
+	reads := benchmarkReads(b)
+	searcher, _ := NewEditSearcher(benchmarkAdapter.Sequence)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, read := range reads {
+			searcher.ThreePrimeMatch(read.Sequence, 4)
+		}
+	}
+}
+
+func BenchmarkEditSearcherSearch(b *testing.B) {
+	reads := benchmarkReads(b)
+	searcher, _ := NewEditSearcher(benchmarkAdapter.Sequence)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, read := range reads {
+			searcher.Search(read.Sequence, 4)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package gobioinfo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ReadPair holds the two mates of a paired-end read
+type ReadPair struct {
+	R1 FASTQRead
+	R2 FASTQRead
+}
+
+// PairedFASTQScanner reads paired-end FASTQ data, either from two files (R1 and R2) or
+// from a single interleaved stream, and checks that the mates stay in step
+type PairedFASTQScanner struct {
+	r1 *FASTQScanner
+	// r2 is nil when reading an interleaved stream, in which case both mates come from r1
+	r2    *FASTQScanner
+	pairs int // number of pairs started so far
+}
+
+// NewPairedFASTQScanner takes two io.Readers, holding the R1 and R2 reads, and returns a
+// PairedFASTQScanner
+func NewPairedFASTQScanner(r1 io.Reader, r2 io.Reader) PairedFASTQScanner {
+	s1 := NewFASTQScanner(r1)
+	s2 := NewFASTQScanner(r2)
+	return PairedFASTQScanner{r1: &s1, r2: &s2}
+}
+
+// NewInterleavedFASTQScanner takes an io.Reader, in which each R1 read is directly followed
+// by its R2 mate, and returns a PairedFASTQScanner
+func NewInterleavedFASTQScanner(r io.Reader) PairedFASTQScanner {
+	s := NewFASTQScanner(r)
+	return PairedFASTQScanner{r1: &s}
+}
+
+// DetectEncoding infers the quality encoding from up to n pairs, as
+// FASTQScanner.DetectEncoding does, and uses it for both mates
+func (s *PairedFASTQScanner) DetectEncoding(n int) (string, error) {
+	if s.r2 == nil {
+		return s.r1.DetectEncoding(2 * n)
+	}
+	encoding, err := s.r1.DetectEncoding(n)
+	s.r2.Encoding = encoding
+	return encoding, err
+}
+
+// Reasons a pair of reads can be rejected, carried in the Err field of a PairError
+var (
+	ErrMateMismatch = errors.New("mate read IDs do not match")
+	ErrMissingMate  = errors.New("one mate is missing")
+)
+
+// PairError is returned by PairedFASTQScanner.NextPair when the two mates of a pair do not
+// belong together, meaning that the R1 and R2 inputs have lost synchronization
+type PairError struct {
+	Pair int    // 1-based number of the pair being read
+	ID1  string // ID of the R1 read, if any
+	ID2  string // ID of the R2 read, if any
+	Err  error  // the reason, one of the Err* values above
+}
+
+func (e *PairError) Error() string {
+	return fmt.Sprintf("paired fastq: pair %d (%q, %q): %v", e.Pair, e.ID1, e.ID2, e.Err)
+}
+
+// Unwrap returns the reason the pair was rejected
+func (e *PairError) Unwrap() error {
+	return e.Err
+}
+
+// NextPair returns the next pair of reads from a PairedFASTQScanner, or io.EOF once both
+// inputs end cleanly together. Mates whose IDs differ, or an input which ends before the
+// other, are reported as a *PairError; once one has been returned the scanner should not
+// be used further.
+func (s *PairedFASTQScanner) NextPair() (ReadPair, error) {
+
+	s.pairs++
+
+	r1, err1 := s.r1.NextRead()
+	if err1 != nil && err1 != io.EOF {
+		return ReadPair{}, err1
+	}
+
+	var r2 FASTQRead
+	var err2 error
+	if s.r2 != nil {
+		r2, err2 = s.r2.NextRead()
+	} else if err1 == nil {
+		r2, err2 = s.r1.NextRead()
+	} else {
+		err2 = io.EOF
+	}
+	if err2 != nil && err2 != io.EOF {
+		return ReadPair{}, err2
+	}
+
+	switch {
+	case err1 == io.EOF && err2 == io.EOF:
+		return ReadPair{}, io.EOF
+	case err1 == io.EOF || err2 == io.EOF:
+		return ReadPair{}, &PairError{Pair: s.pairs, ID1: r1.ID, ID2: r2.ID, Err: ErrMissingMate}
+	case MateID(r1.ID) != MateID(r2.ID):
+		return ReadPair{}, &PairError{Pair: s.pairs, ID1: r1.ID, ID2: r2.ID, Err: ErrMateMismatch}
+	}
+
+	return ReadPair{R1: r1, R2: r2}, nil
+}
+
+// MateID returns the part of a FASTQ read ID which is shared between both mates of a pair:
+// the leading "@", the comment field (eg. the Illumina " 1:N:0:" section) and any "/1" or
+// "/2" suffix are removed.
+//
+//	"@HWI-ST560:155:C574EACXX:3:1101:1159:1937 1:N:0:" -> "HWI-ST560:155:C574EACXX:3:1101:1159:1937"
+//	"@read42/2" -> "read42"
+func MateID(id string) string {
+	id = strings.TrimPrefix(id, "@")
+	if split := strings.IndexAny(id, " \t"); split >= 0 {
+		id = id[:split]
+	}
+	if strings.HasSuffix(id, "/1") || strings.HasSuffix(id, "/2") {
+		id = id[:len(id)-2]
+	}
+	return id
+}
+
+// PairedFASTQWriter writes ReadPairs either split across two outputs (R1 and R2) or
+// interleaved into a single output
+type PairedFASTQWriter struct {
+	w1 *FASTQWriter
+	// w2 is nil when writing interleaved output, in which case both mates go to w1
+	w2 *FASTQWriter
+}
+
+// NewPairedFASTQWriter takes two io.Writers, for the R1 and R2 reads, and returns a
+// PairedFASTQWriter
+func NewPairedFASTQWriter(w1 io.Writer, w2 io.Writer) PairedFASTQWriter {
+	fw1 := NewFASTQWriter(w1)
+	fw2 := NewFASTQWriter(w2)
+	return PairedFASTQWriter{w1: &fw1, w2: &fw2}
+}
+
+// NewInterleavedFASTQWriter takes an io.Writer and returns a PairedFASTQWriter which
+// writes each R1 read directly followed by its R2 mate
+func NewInterleavedFASTQWriter(w io.Writer) PairedFASTQWriter {
+	fw := NewFASTQWriter(w)
+	return PairedFASTQWriter{w1: &fw}
+}
+
+// Write writes both reads of a ReadPair, returns an error
+func (w *PairedFASTQWriter) Write(p ReadPair) error {
+	if err := w.w1.Write(p.R1); err != nil {
+		return err
+	}
+	if w.w2 != nil {
+		return w.w2.Write(p.R2)
+	}
+	return w.w1.Write(p.R2)
+}
+
+// Close flushes and closes the underlying FASTQWriters
+func (w *PairedFASTQWriter) Close() error {
+	err := w.w1.Close()
+	if w.w2 != nil {
+		if err2 := w.w2.Close(); err == nil {
+			err = err2
+		}
+	}
+	return err
+}
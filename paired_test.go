@@ -0,0 +1,127 @@
+package gobioinfo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// func MateID(id string) string {}
+func TestMateID(t *testing.T) {
+	fmt.Println("testing MateID()...")
+
+	type testPair struct {
+		input  string
+		output string
+	}
+
+	testSuite := []testPair{
+		{"@HWI-ST560:155:C574EACXX:3:1101:1159:1937 1:N:0:", "HWI-ST560:155:C574EACXX:3:1101:1159:1937"},
+		{"@HWI-ST560:155:C574EACXX:3:1101:1159:1937 2:N:0:ACGT", "HWI-ST560:155:C574EACXX:3:1101:1159:1937"},
+		{"@read42/1", "read42"},
+		{"@read42/2\tcomment", "read42"},
+		{"read42/3", "read42/3"},
+	}
+
+	for _, elem := range testSuite {
+		if result := MateID(elem.input); result != elem.output {
+			t.Errorf("expected MateID(%q) to be %q, but got %q", elem.input, elem.output, result)
+		}
+	}
+}
+
+// func (s *PairedFASTQScanner) NextPair() (ReadPair, error) {}
+func TestPairedFASTQScannerNextPair(t *testing.T) {
+	fmt.Println("testing PairedFASTQScanner.NextPair()...")
+
+	a1, b1 := "@a/1\nACGT\n+\nIIII\n", "@b 1:N:0:\nAAAA\n+\nIIII\n"
+	a2, b2 := "@a/2\nTTTT\n+\nJJJJ\n", "@b 2:N:0:\nCCCC\n+\nJJJJ\n"
+	r1, r2 := a1+b1, a2+b2
+
+	scanners := []PairedFASTQScanner{
+		NewPairedFASTQScanner(bytes.NewBufferString(r1), bytes.NewBufferString(r2)),
+		NewInterleavedFASTQScanner(bytes.NewBufferString(a1 + a2 + b1 + b2)),
+	}
+
+	for _, scanner := range scanners {
+		var pairs []ReadPair
+		for {
+			pair, err := scanner.NextPair()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal("unexpected error: ", err)
+			}
+			pairs = append(pairs, pair)
+		}
+		if len(pairs) != 2 {
+			t.Fatal("expected 2 pairs, but got ", len(pairs))
+		}
+		if string(pairs[1].R1.Sequence) != "AAAA" || string(pairs[1].R2.Sequence) != "CCCC" {
+			t.Error("mates were not paired in order, got ", pairs[1].R1.ID, pairs[1].R2.ID)
+		}
+	}
+
+	type testPair struct {
+		scanner PairedFASTQScanner
+		pair    int
+		reason  error
+	}
+
+	testSuite := []testPair{
+		{NewPairedFASTQScanner(bytes.NewBufferString(r1), bytes.NewBufferString(b2)), 1, ErrMateMismatch},
+		{NewPairedFASTQScanner(bytes.NewBufferString(r1), bytes.NewBufferString(a2)), 2, ErrMissingMate},
+		{NewPairedFASTQScanner(bytes.NewBufferString(a1), bytes.NewBufferString(r2)), 2, ErrMissingMate},
+		{NewInterleavedFASTQScanner(bytes.NewBufferString(r1)), 1, ErrMateMismatch},
+		{NewInterleavedFASTQScanner(bytes.NewBufferString(a1 + a2 + b1)), 2, ErrMissingMate},
+	}
+
+	for i, elem := range testSuite {
+		var err error
+		for err == nil {
+			_, err = elem.scanner.NextPair()
+		}
+		var pairErr *PairError
+		if !errors.As(err, &pairErr) {
+			t.Errorf("test %d: expected a *PairError, but got %v", i, err)
+			continue
+		}
+		if pairErr.Pair != elem.pair || pairErr.Err != elem.reason {
+			t.Errorf("test %d: expected pair %d, reason %v, but got %v", i, elem.pair, elem.reason, pairErr)
+		}
+	}
+}
+
+// func (w *PairedFASTQWriter) Write(p ReadPair) error {}
+func TestPairedFASTQWriter(t *testing.T) {
+	fmt.Println("testing PairedFASTQWriter...")
+
+	pair := ReadPair{
+		R1: NewFASTQRead("@a/1", []rune("ACGT"), "+", []rune("IIII")),
+		R2: NewFASTQRead("@a/2", []rune("TTTT"), "+", []rune("JJJJ")),
+	}
+
+	var out1, out2, interleaved bytes.Buffer
+
+	split := NewPairedFASTQWriter(&out1, &out2)
+	split.Write(pair)
+	split.Close()
+
+	inter := NewInterleavedFASTQWriter(&interleaved)
+	inter.Write(pair)
+	inter.Write(pair)
+	inter.Close()
+
+	if out1.String() != "@a/1\nACGT\n+\nIIII\n" || out2.String() != "@a/2\nTTTT\n+\nJJJJ\n" {
+		t.Errorf("unexpected split output %q, %q", out1.String(), out2.String())
+	}
+
+	scanner := NewInterleavedFASTQScanner(&interleaved)
+	for i := 0; i < 2; i++ {
+		if _, err := scanner.NextPair(); err != nil {
+			t.Error("could not read back interleaved output: ", err)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package gobioinfo
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+)
+
+/*
+Quality control reports.
+
+A QCStats gathers the statistics of FastQC from a stream of reads, one read at a time,
+so that a report can be made from the same pass over a FASTQ file as the rest of the
+processing. Summary works out the report from what has been gathered so far, and a
+QCSummary can be written as JSON or as a self-contained HTML page (see qcreport.go).
+
+Two parts follow FastQC in sampling the reads rather than keeping them all: the
+duplication levels and overrepresented sequences come from counting the first
+QCOptions.DuplicationSequences different sequences (and later copies of them), with
+reads over 75 bases cut to their first 50.
+*/
+
+// QCOptions holds the settings of a QCStats. The zero value gives the defaults of FastQC.
+type QCOptions struct {
+	// Name names the reads in the report, such as the name of the file
+	Name string
+	// DuplicationSequences is the number of different sequences counted for the
+	// duplication levels, 100000 if it is not set
+	DuplicationSequences int
+	// OverrepresentedFraction is the fraction of the reads a sequence must make up to be
+	// reported as overrepresented, 0.001 if it is not set
+	OverrepresentedFraction float64
+}
+
+// duplicationSequences returns the number of different sequences to count
+func (o QCOptions) duplicationSequences() int {
+	if o.DuplicationSequences <= 0 {
+		return 100000
+	}
+	return o.DuplicationSequences
+}
+
+// overrepresentedFraction returns the fraction of reads making a sequence overrepresented
+func (o QCOptions) overrepresentedFraction() float64 {
+	if o.OverrepresentedFraction <= 0 {
+		return 0.001
+	}
+	return o.OverrepresentedFraction
+}
+
+// maxQCQuality is the highest quality counted separately; higher qualities are counted
+// with it
+const maxQCQuality = 93
+
+// qcPosition holds the counts for one position along the reads
+type qcPosition struct {
+	qualities [maxQCQuality + 1]int
+	bases     [5]int // A, C, G, T and N (or anything else)
+}
+
+// QCStats gathers the statistics of a quality control report from reads. A QCStats must
+// not be used by more than one goroutine at a time.
+type QCStats struct {
+	opts      QCOptions
+	encoding  string
+	reads     int
+	bases     int
+	minLength int
+	maxLength int
+
+	positions         []qcPosition
+	sequenceQualities [maxQCQuality + 1]int // reads by mean quality
+	gc                [101]int              // reads by percentage of GC
+	gcBases           int
+	acgtBases         int
+	lengths           map[int]int
+
+	// the number of copies of each sequence counted for the duplication levels, and the
+	// number of reads counted
+	copies map[string]int
+	copied int
+}
+
+// NewQCStats returns an empty QCStats
+func NewQCStats(opts QCOptions) *QCStats {
+	return &QCStats{opts: opts, lengths: make(map[int]int), copies: make(map[string]int)}
+}
+
+// Add adds a read to the statistics. The qualities of a read without decoded qualities are
+// left out.
+func (s *QCStats) Add(r FASTQRead) {
+
+	length := len(r.Sequence)
+	if s.reads == 0 {
+		s.encoding = r.Encoding
+		s.minLength = length
+	}
+	s.reads++
+	s.bases += length
+	if length < s.minLength {
+		s.minLength = length
+	}
+	if length > s.maxLength {
+		s.maxLength = length
+	}
+	s.lengths[length]++
+
+	for len(s.positions) < length {
+		s.positions = append(s.positions, qcPosition{})
+	}
+
+	gc, acgt := 0, 0
+	for k, base := range r.Sequence {
+		code := 4
+		if base >= 0 && base < 128 && baseCodes[base] >= 0 {
+			code = int(baseCodes[base])
+			acgt++
+			if code == 1 || code == 2 {
+				gc++
+			}
+		}
+		s.positions[k].bases[code]++
+	}
+	if acgt > 0 {
+		s.gc[int(math.Round(ratio(gc, acgt, 100)))]++
+	}
+	s.gcBases += gc
+	s.acgtBases += acgt
+
+	if r.hasQualities() && length > 0 {
+		total := 0
+		for k, q := range r.Decoded {
+			if q > maxQCQuality {
+				q = maxQCQuality
+			}
+			s.positions[k].qualities[q]++
+			total += int(q)
+		}
+		s.sequenceQualities[int(math.Round(ratio(total, length, 1)))]++
+	}
+
+	seq := r.Sequence
+	if len(seq) > 75 {
+		seq = seq[:50]
+	}
+	key := string(seq)
+	if n, ok := s.copies[key]; ok || len(s.copies) < s.opts.duplicationSequences() {
+		s.copies[key] = n + 1
+		s.copied++
+	}
+}
+
+// QCCount is the number of reads with a value, such as a length or a mean quality
+type QCCount struct {
+	Value int `json:"value"`
+	Count int `json:"count"`
+}
+
+// QCPositionQuality is the distribution of the qualities at a position along the reads
+type QCPositionQuality struct {
+	Position      int     `json:"position"` // counting from 1
+	Mean          float64 `json:"mean"`
+	Median        int     `json:"median"`
+	LowerQuartile int     `json:"lower_quartile"`
+	UpperQuartile int     `json:"upper_quartile"`
+	Percentile10  int     `json:"percentile_10"`
+	Percentile90  int     `json:"percentile_90"`
+}
+
+// QCBaseContent is the percentage of each base at a position along the reads
+type QCBaseContent struct {
+	Position int     `json:"position"` // counting from 1
+	A        float64 `json:"a"`
+	C        float64 `json:"c"`
+	G        float64 `json:"g"`
+	T        float64 `json:"t"`
+	N        float64 `json:"n"` // N or any other code
+}
+
+// QCDuplicationLevel is the share of the sequences which occur a number of times
+type QCDuplicationLevel struct {
+	Level string `json:"level"` // the number of copies: "1" to "9", then ">10" to ">10k"
+	// PercentDeduplicated is the percentage of the different sequences at this level, and
+	// PercentTotal the percentage of all reads
+	PercentDeduplicated float64 `json:"percent_deduplicated"`
+	PercentTotal        float64 `json:"percent_total"`
+}
+
+// QCOverrepresented is a sequence making up more than OverrepresentedFraction of the reads
+type QCOverrepresented struct {
+	Sequence string  `json:"sequence"`
+	Count    int     `json:"count"`
+	Percent  float64 `json:"percent"`
+}
+
+// QCSummary is a quality control report
+type QCSummary struct {
+	Name       string  `json:"name,omitempty"`
+	Encoding   string  `json:"encoding,omitempty"`
+	Reads      int     `json:"reads"`
+	Bases      int     `json:"bases"`
+	MinLength  int     `json:"min_length"`
+	MaxLength  int     `json:"max_length"`
+	MeanLength float64 `json:"mean_length"`
+	GC         float64 `json:"gc_percent"`
+
+	PerBaseQuality     []QCPositionQuality `json:"per_base_quality"`
+	PerSequenceQuality []QCCount           `json:"per_sequence_quality"` // reads by mean quality
+	PerBaseContent     []QCBaseContent     `json:"per_base_content"`
+	GCContent          []QCCount           `json:"gc_content"` // reads by percentage of GC
+	LengthDistribution []QCCount           `json:"length_distribution"`
+	// DeduplicatedPercent is the percentage of the reads which would be left if each
+	// sequence were kept once
+	DeduplicatedPercent float64              `json:"deduplicated_percent"`
+	DuplicationLevels   []QCDuplicationLevel `json:"duplication_levels"`
+	Overrepresented     []QCOverrepresented  `json:"overrepresented_sequences"`
+}
+
+// duplicationLevels are the lowest number of copies of each level of QCDuplicationLevel
+var duplicationLevels = []struct {
+	copies int
+	level  string
+}{
+	{1, "1"}, {2, "2"}, {3, "3"}, {4, "4"}, {5, "5"}, {6, "6"}, {7, "7"}, {8, "8"}, {9, "9"},
+	{10, ">10"}, {50, ">50"}, {100, ">100"}, {500, ">500"}, {1000, ">1k"}, {5000, ">5k"}, {10000, ">10k"},
+}
+
+// percentile returns the lowest value with at least p percent of the counts at or below it
+func percentile(counts []int, total int, p float64) int {
+	cumulative := 0
+	for value, c := range counts {
+		cumulative += c
+		if float64(cumulative) >= float64(total)*p/100 && cumulative > 0 {
+			return value
+		}
+	}
+	return 0
+}
+
+// sparseCounts returns the counts which are not 0, indexed by value
+func sparseCounts(counts []int) []QCCount {
+	var sparse []QCCount
+	for value, c := range counts {
+		if c > 0 {
+			sparse = append(sparse, QCCount{value, c})
+		}
+	}
+	return sparse
+}
+
+// Summary works out the report from the reads added so far
+func (s *QCStats) Summary() QCSummary {
+
+	summary := QCSummary{
+		Name:               s.opts.Name,
+		Encoding:           s.encoding,
+		Reads:              s.reads,
+		Bases:              s.bases,
+		MinLength:          s.minLength,
+		MaxLength:          s.maxLength,
+		MeanLength:         ratio(s.bases, s.reads, 1),
+		GC:                 ratio(s.gcBases, s.acgtBases, 100),
+		PerSequenceQuality: sparseCounts(s.sequenceQualities[:]),
+		GCContent:          sparseCounts(s.gc[:]),
+	}
+
+	for k, p := range s.positions {
+		total, sum := 0, 0
+		for q, c := range p.qualities {
+			total += c
+			sum += q * c
+		}
+		if total > 0 {
+			summary.PerBaseQuality = append(summary.PerBaseQuality, QCPositionQuality{
+				Position:      k + 1,
+				Mean:          ratio(sum, total, 1),
+				Median:        percentile(p.qualities[:], total, 50),
+				LowerQuartile: percentile(p.qualities[:], total, 25),
+				UpperQuartile: percentile(p.qualities[:], total, 75),
+				Percentile10:  percentile(p.qualities[:], total, 10),
+				Percentile90:  percentile(p.qualities[:], total, 90),
+			})
+		}
+
+		bases := p.bases[0] + p.bases[1] + p.bases[2] + p.bases[3] + p.bases[4]
+		summary.PerBaseContent = append(summary.PerBaseContent, QCBaseContent{
+			Position: k + 1,
+			A:        ratio(p.bases[0], bases, 100),
+			C:        ratio(p.bases[1], bases, 100),
+			G:        ratio(p.bases[2], bases, 100),
+			T:        ratio(p.bases[3], bases, 100),
+			N:        ratio(p.bases[4], bases, 100),
+		})
+	}
+
+	for length, c := range s.lengths {
+		summary.LengthDistribution = append(summary.LengthDistribution, QCCount{length, c})
+	}
+	sort.Slice(summary.LengthDistribution, func(x int, y int) bool {
+		return summary.LengthDistribution[x].Value < summary.LengthDistribution[y].Value
+	})
+
+	// the different sequences and reads at each duplication level
+	sequences := make([]int, len(duplicationLevels))
+	reads := make([]int, len(duplicationLevels))
+	for seq, n := range s.copies {
+		level := sort.Search(len(duplicationLevels), func(k int) bool { return duplicationLevels[k].copies > n }) - 1
+		sequences[level]++
+		reads[level] += n
+
+		if float64(n) > float64(s.reads)*s.opts.overrepresentedFraction() {
+			summary.Overrepresented = append(summary.Overrepresented, QCOverrepresented{seq, n, ratio(n, s.reads, 100)})
+		}
+	}
+	summary.DeduplicatedPercent = ratio(len(s.copies), s.copied, 100)
+	for k, level := range duplicationLevels {
+		summary.DuplicationLevels = append(summary.DuplicationLevels, QCDuplicationLevel{
+			Level:               level.level,
+			PercentDeduplicated: ratio(sequences[k], len(s.copies), 100),
+			PercentTotal:        ratio(reads[k], s.copied, 100),
+		})
+	}
+	sort.Slice(summary.Overrepresented, func(x int, y int) bool {
+		a, b := summary.Overrepresented[x], summary.Overrepresented[y]
+		return a.Count > b.Count || (a.Count == b.Count && a.Sequence < b.Sequence)
+	})
+
+	return summary
+}
+
+// WriteJSON writes the report as an indented JSON object
+func (s QCSummary) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
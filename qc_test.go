@@ -0,0 +1,126 @@
+package gobioinfo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// qcTestReads are the reads of the quality control tests
+func qcTestReads() []FASTQRead {
+	return []FASTQRead{
+		qualityRead("ACGT", 10, 20, 30, 40),
+		qualityRead("ACGT", 20, 20, 20, 20),
+		qualityRead("GGCC", 30, 30, 30, 30),
+		qualityRead("AANTA", 40, 30, 20, 10, 0),
+	}
+}
+
+// func (s *QCStats) Summary() QCSummary
+func TestQCSummary(t *testing.T) {
+	fmt.Println("testing QCStats.Summary()...")
+
+	stats := NewQCStats(QCOptions{Name: "test", OverrepresentedFraction: 0.3})
+	for _, r := range qcTestReads() {
+		stats.Add(r)
+	}
+	s := stats.Summary()
+
+	if s.Name != "test" || s.Encoding != EncodingPHRED33 || s.Reads != 4 || s.Bases != 17 ||
+		s.MinLength != 4 || s.MaxLength != 5 || s.MeanLength != 4.25 || s.GC != 50 {
+		t.Errorf("expected the basic statistics test, phred+33, 4, 17, 4, 5, 4.25, 50, but got %s, %s, %d, %d, %d, %d, %v, %v",
+			s.Name, s.Encoding, s.Reads, s.Bases, s.MinLength, s.MaxLength, s.MeanLength, s.GC)
+	}
+
+	type testPair struct {
+		name     string
+		got      interface{}
+		expected interface{}
+	}
+
+	testSuite := []testPair{
+		{"per base quality at 1", s.PerBaseQuality[0], QCPositionQuality{1, 25, 20, 10, 30, 10, 40}},
+		{"per base quality at 5", s.PerBaseQuality[4], QCPositionQuality{5, 0, 0, 0, 0, 0, 0}},
+		{"per sequence quality", s.PerSequenceQuality, []QCCount{{20, 2}, {25, 1}, {30, 1}}},
+		{"per base content at 3", s.PerBaseContent[2], QCBaseContent{3, 0, 25, 50, 0, 25}},
+		{"GC content", s.GCContent, []QCCount{{0, 1}, {50, 2}, {100, 1}}},
+		{"length distribution", s.LengthDistribution, []QCCount{{4, 3}, {5, 1}}},
+		{"deduplicated percent", s.DeduplicatedPercent, 75.0},
+		{"duplication level 1", s.DuplicationLevels[0], QCDuplicationLevel{"1", ratio(2, 3, 100), 50}},
+		{"duplication level 2", s.DuplicationLevels[1], QCDuplicationLevel{"2", ratio(1, 3, 100), 50}},
+		{"duplication level >10k", s.DuplicationLevels[len(s.DuplicationLevels)-1], QCDuplicationLevel{">10k", 0, 0}},
+		{"overrepresented", s.Overrepresented, []QCOverrepresented{{"ACGT", 2, 50}}},
+	}
+
+	for i, test := range testSuite {
+		if !reflect.DeepEqual(test.got, test.expected) {
+			t.Errorf("test %d: expected %s %+v, but got %+v", i, test.name, test.expected, test.got)
+		}
+	}
+
+	// only the first sequence is counted for the duplication levels, and a long read is cut
+	// to its first 50 bases
+	long := strings.Repeat("ACGT", 20)
+	stats = NewQCStats(QCOptions{DuplicationSequences: 1})
+	stats.Add(qualityRead(long))
+	stats.Add(qualityRead(long[:50] + "TTTT"))
+	stats.Add(qualityRead(long[:50] + "TTTTTTTTTTTTTTTTTTTTTTTTTT"))
+	stats.Add(qualityRead("GGCC"))
+	s = stats.Summary()
+	if s.DeduplicatedPercent != 50 || len(s.Overrepresented) != 1 || s.Overrepresented[0].Count != 2 || s.Overrepresented[0].Sequence != long[:50] {
+		t.Errorf("expected 50%% deduplicated and %s overrepresented twice, but got %v%% and %+v", long[:50], s.DeduplicatedPercent, s.Overrepresented)
+	}
+}
+
+// func (s QCSummary) WriteJSON(w io.Writer) error
+// func (s QCSummary) WriteHTML(w io.Writer) error
+func TestQCReports(t *testing.T) {
+	fmt.Println("testing QCSummary.WriteJSON() and QCSummary.WriteHTML()...")
+
+	stats := NewQCStats(QCOptions{Name: "<reads>", OverrepresentedFraction: 0.3})
+	for _, r := range qcTestReads() {
+		stats.Add(r)
+	}
+	s := stats.Summary()
+
+	var buf bytes.Buffer
+	if err := s.WriteJSON(&buf); err != nil {
+		t.Errorf("expected no error writing JSON, but got %v", err)
+	}
+	var decoded QCSummary
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || !reflect.DeepEqual(decoded, s) {
+		t.Errorf("expected the JSON to decode to the summary, but got %+v (%v)", decoded, err)
+	}
+	if !strings.Contains(buf.String(), `"gc_percent": 50`) {
+		t.Errorf("expected the JSON to hold \"gc_percent\": 50, but got %s", buf.String())
+	}
+
+	for i, summary := range []QCSummary{s, NewQCStats(QCOptions{}).Summary()} {
+		buf.Reset()
+		if err := summary.WriteHTML(&buf); err != nil {
+			t.Errorf("test %d: expected no error writing HTML, but got %v", i, err)
+		}
+		page := buf.String()
+		for _, section := range []string{"Basic statistics", "Per base sequence quality", "Per sequence quality scores",
+			"Per base sequence content", "Per sequence GC content", "Per base N content",
+			"Sequence length distribution", "Sequence duplication levels", "Overrepresented sequences"} {
+			if !strings.Contains(page, "<h2>"+section+"</h2>") {
+				t.Errorf("test %d: expected a %s section, but got none", i, section)
+			}
+		}
+		if !strings.HasSuffix(page, "</html>\n") {
+			t.Errorf("test %d: expected the page to end with </html>", i)
+		}
+	}
+
+	buf.Reset()
+	s.WriteHTML(&buf)
+	page := buf.String()
+	if !strings.Contains(page, "<title>Quality control report: &lt;reads&gt;</title>") ||
+		!strings.Contains(page, "<td class=\"sequence\">ACGT</td>") || strings.Count(page, "<svg") != 7 {
+		t.Errorf("expected an escaped title, ACGT as overrepresented and 7 charts, but got %s", page)
+	}
+}
@@ -0,0 +1,282 @@
+package gobioinfo
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*
+The HTML quality control report.
+
+The report is a single page with no scripts or links to other files, so it can be
+mailed or archived along with the data: each chart is drawn as inline SVG, in the
+layout of the FastQC report.
+*/
+
+// size in pixels of the charts, and of the margins around the plot inside them
+const (
+	chartWidth  = 800
+	chartHeight = 300
+	chartLeft   = 50
+	chartRight  = 20
+	chartTop    = 20
+	chartBottom = 40
+)
+
+// qcSeries is a line on a chart
+type qcSeries struct {
+	name   string
+	color  string
+	values []float64
+}
+
+// chart is an SVG chart of n columns with a y axis from 0 to yMax, being written to buf
+type chart struct {
+	buf  *strings.Builder
+	n    int
+	yMax float64
+}
+
+// newChart starts a chart of n columns, labelled along the x axis by label(k), with the
+// y axis from 0 to yMax. The plot is drawn between the axes by the caller.
+func newChart(buf *strings.Builder, n int, yMax float64, xName string, yName string, label func(k int) string) chart {
+
+	c := chart{buf: buf, n: n, yMax: yMax}
+	fmt.Fprintf(buf, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\" font-family=\"sans-serif\" font-size=\"10\">\n",
+		chartWidth, chartHeight, chartWidth, chartHeight)
+
+	// the y axis, with five ticks
+	for k := 0; k <= 5; k++ {
+		v := yMax * float64(k) / 5
+		y := c.y(v)
+		fmt.Fprintf(buf, "<line x1=\"%d\" y1=\"%.1f\" x2=\"%d\" y2=\"%.1f\" stroke=\"#eeeeee\"/>\n", chartLeft, y, chartWidth-chartRight, y)
+		fmt.Fprintf(buf, "<text x=\"%d\" y=\"%.1f\" text-anchor=\"end\" dominant-baseline=\"central\">%s</text>\n", chartLeft-4, y, strconv.FormatFloat(v, 'f', -1, 64))
+	}
+
+	// the x axis, labelling at most about 25 columns
+	step := (n + 24) / 25
+	if step < 1 {
+		step = 1
+	}
+	for k := 0; k < n; k += step {
+		fmt.Fprintf(buf, "<text x=\"%.1f\" y=\"%d\" text-anchor=\"middle\">%s</text>\n", c.x(k), chartHeight-chartBottom+14, html.EscapeString(label(k)))
+	}
+
+	fmt.Fprintf(buf, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"black\"/>\n", chartLeft, chartTop, chartLeft, chartHeight-chartBottom)
+	fmt.Fprintf(buf, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"black\"/>\n", chartLeft, chartHeight-chartBottom, chartWidth-chartRight, chartHeight-chartBottom)
+	fmt.Fprintf(buf, "<text x=\"%d\" y=\"%d\" text-anchor=\"middle\">%s</text>\n", (chartLeft+chartWidth-chartRight)/2, chartHeight-6, html.EscapeString(xName))
+	fmt.Fprintf(buf, "<text x=\"12\" y=\"%d\" text-anchor=\"middle\" transform=\"rotate(-90 12 %d)\">%s</text>\n", (chartTop+chartHeight-chartBottom)/2, (chartTop+chartHeight-chartBottom)/2, html.EscapeString(yName))
+
+	return c
+}
+
+// x returns the position of the middle of column k
+func (c chart) x(k int) float64 {
+	return chartLeft + (float64(k)+0.5)*float64(chartWidth-chartLeft-chartRight)/float64(c.n)
+}
+
+// columnWidth returns the width of a column
+func (c chart) columnWidth() float64 {
+	return float64(chartWidth-chartLeft-chartRight) / float64(c.n)
+}
+
+// y returns the position of the value v, which is kept within the chart
+func (c chart) y(v float64) float64 {
+	v = math.Max(0, math.Min(v, c.yMax))
+	return chartTop + float64(chartHeight-chartTop-chartBottom)*(1-v/c.yMax)
+}
+
+// lines draws each series as a line, with a legend
+func (c chart) lines(series []qcSeries) {
+	for k, s := range series {
+		points := make([]string, len(s.values))
+		for i, v := range s.values {
+			points[i] = fmt.Sprintf("%.1f,%.1f", c.x(i), c.y(v))
+		}
+		fmt.Fprintf(c.buf, "<polyline points=\"%s\" fill=\"none\" stroke=\"%s\" stroke-width=\"1.5\"/>\n", strings.Join(points, " "), s.color)
+		if s.name != "" {
+			fmt.Fprintf(c.buf, "<text x=\"%d\" y=\"%d\" text-anchor=\"end\" fill=\"%s\">%s</text>\n", chartWidth-chartRight-4, chartTop+12+12*k, s.color, html.EscapeString(s.name))
+		}
+	}
+}
+
+// end closes the chart
+func (c chart) end() {
+	c.buf.WriteString("</svg>\n")
+}
+
+// lineChart draws a chart of the series, all of n values
+func lineChart(buf *strings.Builder, n int, yMax float64, xName string, yName string, label func(k int) string, series ...qcSeries) {
+	if n == 0 {
+		buf.WriteString("<p>No reads.</p>\n")
+		return
+	}
+	c := newChart(buf, n, yMax, xName, yName, label)
+	c.lines(series)
+	c.end()
+}
+
+// denseCounts returns the counts of the values from lowest to highest as percentages of
+// total, with 0 for those missing
+func denseCounts(counts []QCCount, lowest int, highest int, total int) []float64 {
+	values := make([]float64, highest-lowest+1)
+	for _, c := range counts {
+		values[c.Value-lowest] = ratio(c.Count, total, 100)
+	}
+	return values
+}
+
+// maxValue returns the highest of the values, or 1 if they are all lower, rounded up to a
+// multiple of 5 so the axis ticks are round numbers
+func maxValue(values ...[]float64) float64 {
+	highest := 1.0
+	for _, vs := range values {
+		for _, v := range vs {
+			highest = math.Max(highest, v)
+		}
+	}
+	return math.Ceil(highest/5) * 5
+}
+
+// qualityChart draws the per base qualities as FastQC does: a box for the quartiles, a
+// red line for the median and whiskers for the 10th and 90th percentiles at each
+// position, and a blue line for the mean, over bands marking good (28 and above),
+// reasonable (20 to 28) and poor qualities
+func qualityChart(buf *strings.Builder, positions []QCPositionQuality) {
+
+	if len(positions) == 0 {
+		buf.WriteString("<p>No reads with qualities.</p>\n")
+		return
+	}
+
+	yMax := 40.0
+	for _, p := range positions {
+		yMax = math.Max(yMax, float64(p.Percentile90+2))
+	}
+	yMax = math.Ceil(yMax/5) * 5
+
+	c := newChart(buf, len(positions), yMax, "position in read (bp)", "quality", func(k int) string { return strconv.Itoa(positions[k].Position) })
+
+	for _, band := range []struct {
+		low, high float64
+		color     string
+	}{{28, yMax, "#e6f5e6"}, {20, 28, "#fdf3e1"}, {0, 20, "#fbe5e5"}} {
+		fmt.Fprintf(buf, "<rect x=\"%d\" y=\"%.1f\" width=\"%d\" height=\"%.1f\" fill=\"%s\"/>\n",
+			chartLeft+1, c.y(band.high), chartWidth-chartLeft-chartRight, c.y(band.low)-c.y(band.high), band.color)
+	}
+
+	width := c.columnWidth() * 0.6
+	means := make([]float64, len(positions))
+	for k, p := range positions {
+		x := c.x(k)
+		fmt.Fprintf(buf, "<line x1=\"%.1f\" y1=\"%.1f\" x2=\"%.1f\" y2=\"%.1f\" stroke=\"black\"/>\n", x, c.y(float64(p.Percentile10)), x, c.y(float64(p.Percentile90)))
+		fmt.Fprintf(buf, "<rect x=\"%.1f\" y=\"%.1f\" width=\"%.1f\" height=\"%.1f\" fill=\"#ffdd55\" stroke=\"black\" stroke-width=\"0.5\"/>\n",
+			x-width/2, c.y(float64(p.UpperQuartile)), width, c.y(float64(p.LowerQuartile))-c.y(float64(p.UpperQuartile)))
+		fmt.Fprintf(buf, "<line x1=\"%.1f\" y1=\"%.1f\" x2=\"%.1f\" y2=\"%.1f\" stroke=\"red\"/>\n", x-width/2, c.y(float64(p.Median)), x+width/2, c.y(float64(p.Median)))
+		means[k] = p.Mean
+	}
+	c.lines([]qcSeries{{"mean", "blue", means}})
+	c.end()
+}
+
+// WriteHTML writes the report as a self-contained HTML page
+func (s QCSummary) WriteHTML(w io.Writer) error {
+
+	var buf strings.Builder
+	title := "Quality control report"
+	if s.Name != "" {
+		title += ": " + s.Name
+	}
+
+	fmt.Fprintf(&buf, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n", html.EscapeString(title))
+	buf.WriteString("<style>\nbody { font-family: sans-serif; margin: 2em; }\ntable { border-collapse: collapse; }\n" +
+		"td, th { border: 1px solid #cccccc; padding: 0.2em 0.6em; text-align: left; }\n" +
+		"td.sequence { font-family: monospace; }\nh2 { margin-top: 1.5em; }\n</style>\n</head>\n<body>\n")
+	fmt.Fprintf(&buf, "<h1>%s</h1>\n", html.EscapeString(title))
+
+	buf.WriteString("<h2>Basic statistics</h2>\n<table>\n")
+	for _, row := range [][2]string{
+		{"Reads", strconv.Itoa(s.Reads)},
+		{"Bases", strconv.Itoa(s.Bases)},
+		{"Length", fmt.Sprintf("%d-%d (mean %.1f)", s.MinLength, s.MaxLength, s.MeanLength)},
+		{"GC", fmt.Sprintf("%.1f%%", s.GC)},
+		{"Encoding", s.Encoding},
+		{"Sequences left if deduplicated", fmt.Sprintf("%.1f%%", s.DeduplicatedPercent)},
+	} {
+		fmt.Fprintf(&buf, "<tr><th>%s</th><td>%s</td></tr>\n", row[0], html.EscapeString(row[1]))
+	}
+	buf.WriteString("</table>\n")
+
+	buf.WriteString("<h2>Per base sequence quality</h2>\n")
+	qualityChart(&buf, s.PerBaseQuality)
+
+	buf.WriteString("<h2>Per sequence quality scores</h2>\n")
+	if len(s.PerSequenceQuality) == 0 {
+		buf.WriteString("<p>No reads with qualities.</p>\n")
+	} else {
+		lowest, highest := s.PerSequenceQuality[0].Value, s.PerSequenceQuality[len(s.PerSequenceQuality)-1].Value
+		qualities := denseCounts(s.PerSequenceQuality, lowest, highest, s.Reads)
+		lineChart(&buf, len(qualities), maxValue(qualities), "mean sequence quality", "% of reads",
+			func(k int) string { return strconv.Itoa(lowest + k) }, qcSeries{"", "red", qualities})
+	}
+
+	position := func(k int) string { return strconv.Itoa(s.PerBaseContent[k].Position) }
+	var a, c, g, t, n []float64
+	for _, p := range s.PerBaseContent {
+		a, c, g, t, n = append(a, p.A), append(c, p.C), append(g, p.G), append(t, p.T), append(n, p.N)
+	}
+
+	buf.WriteString("<h2>Per base sequence content</h2>\n")
+	lineChart(&buf, len(s.PerBaseContent), 100, "position in read (bp)", "% of bases", position,
+		qcSeries{"A", "green", a}, qcSeries{"C", "blue", c}, qcSeries{"G", "black", g}, qcSeries{"T", "red", t})
+
+	buf.WriteString("<h2>Per sequence GC content</h2>\n")
+	gc := denseCounts(s.GCContent, 0, 100, s.Reads)
+	if s.Reads == 0 {
+		gc = nil
+	}
+	lineChart(&buf, len(gc), maxValue(gc), "mean GC content (%)", "% of reads", strconv.Itoa, qcSeries{"", "red", gc})
+
+	buf.WriteString("<h2>Per base N content</h2>\n")
+	lineChart(&buf, len(s.PerBaseContent), maxValue(n), "position in read (bp)", "% of bases", position, qcSeries{"N", "red", n})
+
+	buf.WriteString("<h2>Sequence length distribution</h2>\n")
+	var lengths []float64
+	if s.Reads > 0 {
+		lengths = denseCounts(s.LengthDistribution, s.MinLength, s.MaxLength, s.Reads)
+	}
+	lineChart(&buf, len(lengths), maxValue(lengths), "sequence length (bp)", "% of reads",
+		func(k int) string { return strconv.Itoa(s.MinLength + k) }, qcSeries{"", "red", lengths})
+
+	buf.WriteString("<h2>Sequence duplication levels</h2>\n")
+	var deduplicated, total []float64
+	for _, level := range s.DuplicationLevels {
+		deduplicated, total = append(deduplicated, level.PercentDeduplicated), append(total, level.PercentTotal)
+	}
+	if s.Reads == 0 {
+		deduplicated, total = nil, nil
+	}
+	lineChart(&buf, len(total), 100, "sequence duplication level", "% of sequences",
+		func(k int) string { return s.DuplicationLevels[k].Level },
+		qcSeries{"% of deduplicated", "red", deduplicated}, qcSeries{"% of total", "blue", total})
+
+	buf.WriteString("<h2>Overrepresented sequences</h2>\n")
+	if len(s.Overrepresented) == 0 {
+		buf.WriteString("<p>No overrepresented sequences.</p>\n")
+	} else {
+		buf.WriteString("<table>\n<tr><th>Sequence</th><th>Count</th><th>Percentage</th></tr>\n")
+		for _, o := range s.Overrepresented {
+			fmt.Fprintf(&buf, "<tr><td class=\"sequence\">%s</td><td>%d</td><td>%.2f%%</td></tr>\n", html.EscapeString(o.Sequence), o.Count, o.Percent)
+		}
+		buf.WriteString("</table>\n")
+	}
+
+	buf.WriteString("</body>\n</html>\n")
+
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
@@ -0,0 +1,143 @@
+package gobioinfo
+
+/*
+Quality trimming.
+
+Each of these trims low quality (or N) bases from the ends of a read using its decoded
+PHRED scores, and returns the trimmed read along with the number of bases removed. The
+trimmed read shares its sequence and qualities with the read given, cut consistently.
+A read without decoded qualities (PHRED.Decoded not the length of the sequence) is
+returned as it is by the quality based trims.
+
+QualityTrim and NextSeqTrim use the algorithm of BWA and cutadapt (-q and
+--nextseq-trim): going back from the 3' end, the cutoff minus the quality of each base
+is added up, and the read is cut where that sum is highest, so that a few good bases
+among bad ones are trimmed too. LeadingTrim, TrailingTrim and SlidingWindowTrim work as
+the Trimmomatic steps of the same names.
+*/
+
+// hasQualities reports whether the read has a decoded quality for each base
+func (r FASTQRead) hasQualities() bool {
+	return len(r.Decoded) == len(r.Sequence)
+}
+
+// mottTrimIndex returns where to cut the read to trim its 3' end with the BWA algorithm,
+// using quality(i) for the quality of base i
+func mottTrimIndex(length int, cutoff int, quality func(i int) int) int {
+	stop := length
+	sum, best := 0, 0
+	for i := length - 1; i >= 0; i-- {
+		sum += cutoff - quality(i)
+		if sum < 0 {
+			break
+		}
+		if sum > best {
+			best, stop = sum, i
+		}
+	}
+	return stop
+}
+
+// QualityTrim trims the 3' end of the read with the BWA algorithm (cutadapt -q cutoff)
+func (r FASTQRead) QualityTrim(cutoff int) (FASTQRead, int) {
+	if !r.hasQualities() {
+		return r, 0
+	}
+	stop := mottTrimIndex(len(r.Sequence), cutoff, func(i int) int { return int(r.Decoded[i]) })
+	return r.sub(0, stop), len(r.Sequence) - stop
+}
+
+// NextSeqTrim trims the 3' end of a read from a two-color instrument (NextSeq, NovaSeq),
+// where no signal is read as a G of high quality, so that reads run into long stretches of
+// G after their end. It works as QualityTrim, except that each G is taken to be of a
+// quality just below the cutoff (cutadapt --nextseq-trim).
+func (r FASTQRead) NextSeqTrim(cutoff int) (FASTQRead, int) {
+	if !r.hasQualities() {
+		return r, 0
+	}
+	stop := mottTrimIndex(len(r.Sequence), cutoff, func(i int) int {
+		if r.Sequence[i] == 'G' || r.Sequence[i] == 'g' {
+			return cutoff - 1
+		}
+		return int(r.Decoded[i])
+	})
+	return r.sub(0, stop), len(r.Sequence) - stop
+}
+
+// LeadingTrim removes bases from the 5' end of the read while their quality is below
+// quality (Trimmomatic LEADING)
+func (r FASTQRead) LeadingTrim(quality int) (FASTQRead, int) {
+	if !r.hasQualities() {
+		return r, 0
+	}
+	start := 0
+	for start < len(r.Sequence) && int(r.Decoded[start]) < quality {
+		start++
+	}
+	return r.sub(start, len(r.Sequence)), start
+}
+
+// TrailingTrim removes bases from the 3' end of the read while their quality is below
+// quality (Trimmomatic TRAILING)
+func (r FASTQRead) TrailingTrim(quality int) (FASTQRead, int) {
+	if !r.hasQualities() {
+		return r, 0
+	}
+	stop := len(r.Sequence)
+	for stop > 0 && int(r.Decoded[stop-1]) < quality {
+		stop--
+	}
+	return r.sub(0, stop), len(r.Sequence) - stop
+}
+
+// SlidingWindowTrim scans the read from its 5' end with a window of window bases, and cuts
+// it at the first window whose average quality is below quality, keeping the bases at the
+// start of that window which are of at least that quality (Trimmomatic SLIDINGWINDOW). A
+// read shorter than the window is taken as a single window.
+func (r FASTQRead) SlidingWindowTrim(window int, quality int) (FASTQRead, int) {
+	if !r.hasQualities() || window < 1 {
+		return r, 0
+	}
+
+	length := len(r.Sequence)
+	if window > length {
+		window = length
+	}
+
+	// the windows are compared by their totals, to avoid rounding
+	required := window * quality
+	total := 0
+	for i := 0; i < window; i++ {
+		total += int(r.Decoded[i])
+	}
+
+	stop := length
+	for start := 0; start+window <= length; start++ {
+		if start > 0 {
+			total += int(r.Decoded[start+window-1]) - int(r.Decoded[start-1])
+		}
+		if total < required {
+			stop = start
+			for stop < start+window && int(r.Decoded[stop]) >= quality {
+				stop++
+			}
+			break
+		}
+	}
+
+	return r.sub(0, stop), length - stop
+}
+
+// TrimNs removes the Ns from both ends of the read (cutadapt --trim-n)
+func (r FASTQRead) TrimNs() (FASTQRead, int) {
+	isN := func(base rune) bool { return base == 'N' || base == 'n' }
+
+	start, stop := 0, len(r.Sequence)
+	for start < stop && isN(r.Sequence[start]) {
+		start++
+	}
+	for stop > start && isN(r.Sequence[stop-1]) {
+		stop--
+	}
+	return r.sub(start, stop), len(r.Sequence) - (stop - start)
+}
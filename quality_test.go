@@ -0,0 +1,90 @@
+package gobioinfo
+
+import (
+	"fmt"
+	"testing"
+)
+
+// qualityRead returns a read of the bases with the decoded qualities, encoded as phred+33
+func qualityRead(bases string, qualities ...uint8) FASTQRead {
+	encoded := make([]rune, len(qualities))
+	for i, q := range qualities {
+		encoded[i] = rune(q) + '!'
+	}
+	return NewFASTQRead("@read", []rune(bases), "+", encoded)
+}
+
+func TestQualityTrims(t *testing.T) {
+	fmt.Println("testing QualityTrim(), NextSeqTrim(), LeadingTrim(), TrailingTrim(), SlidingWindowTrim() and TrimNs()...")
+
+	type testPair struct {
+		name    string
+		trim    func(r FASTQRead) (FASTQRead, int)
+		read    FASTQRead
+		trimmed string
+		removed int
+	}
+
+	quality := func(cutoff int) func(r FASTQRead) (FASTQRead, int) {
+		return func(r FASTQRead) (FASTQRead, int) { return r.QualityTrim(cutoff) }
+	}
+	nextSeq := func(cutoff int) func(r FASTQRead) (FASTQRead, int) {
+		return func(r FASTQRead) (FASTQRead, int) { return r.NextSeqTrim(cutoff) }
+	}
+	leading := func(q int) func(r FASTQRead) (FASTQRead, int) {
+		return func(r FASTQRead) (FASTQRead, int) { return r.LeadingTrim(q) }
+	}
+	trailing := func(q int) func(r FASTQRead) (FASTQRead, int) {
+		return func(r FASTQRead) (FASTQRead, int) { return r.TrailingTrim(q) }
+	}
+	window := func(size int, q int) func(r FASTQRead) (FASTQRead, int) {
+		return func(r FASTQRead) (FASTQRead, int) { return r.SlidingWindowTrim(size, q) }
+	}
+	ns := func(r FASTQRead) (FASTQRead, int) { return r.TrimNs() }
+
+	testSuite := []testPair{
+		// the example from the cutadapt documentation
+		{"QualityTrim", quality(10), qualityRead("ACGTACGTAC", 42, 40, 26, 27, 8, 7, 11, 4, 2, 3), "ACGT", 6},
+		{"QualityTrim", quality(10), qualityRead("ACGTAC", 42, 40, 26, 27, 30, 30), "ACGTAC", 0},
+		{"QualityTrim", quality(10), qualityRead("ACG", 2, 2, 2), "", 3},
+		{"QualityTrim", quality(0), qualityRead("ACG", 0, 0, 0), "ACG", 0},
+		// a run of Gs is trimmed however good its qualities, but only at the 3' end
+		{"NextSeqTrim", nextSeq(20), qualityRead("AGGTGGGGGGGG", 40, 40, 40, 40, 40, 40, 40, 40, 40, 40, 40, 40), "AGGT", 8},
+		{"NextSeqTrim", nextSeq(20), qualityRead("ACGTGGA", 40, 40, 40, 40, 40, 40, 40), "ACGTGGA", 0},
+		{"NextSeqTrim", nextSeq(20), qualityRead("ACGTGGA", 40, 40, 40, 40, 40, 40, 2), "ACGT", 3},
+		{"LeadingTrim", leading(3), qualityRead("ACGTA", 2, 3, 30, 30, 2), "CGTA", 1},
+		{"LeadingTrim", leading(3), qualityRead("AC", 2, 2), "", 2},
+		{"TrailingTrim", trailing(3), qualityRead("ACGTA", 2, 3, 30, 1, 2), "ACG", 2},
+		{"SlidingWindowTrim", window(4, 20), qualityRead("ACGTACGTAC", 30, 30, 30, 30, 10, 10, 30, 5, 5, 5), "ACGT", 6},
+		// the good bases at the start of the failing window are kept
+		{"SlidingWindowTrim", window(3, 20), qualityRead("ACGTACG", 30, 30, 30, 25, 5, 5, 5), "ACGT", 3},
+		{"SlidingWindowTrim", window(4, 20), qualityRead("ACGTACGT", 30, 30, 30, 30, 30, 30, 30, 30), "ACGTACGT", 0},
+		{"SlidingWindowTrim", window(4, 20), qualityRead("AC", 10, 10), "", 2},
+		{"TrimNs", ns, qualityRead("NNACNTNn", 1, 2, 3, 4, 5, 6, 7, 8), "ACNT", 4},
+		{"TrimNs", ns, qualityRead("NNNN", 1, 2, 3, 4), "", 4},
+		// a read without qualities is only trimmed of Ns
+		{"QualityTrim", quality(10), FASTQRead{DNASequence: DNASequence{Sequence: NucleotideSequence("ACGT")}}, "ACGT", 0},
+		{"TrimNs", ns, FASTQRead{DNASequence: DNASequence{Sequence: NucleotideSequence("NACGN")}}, "ACG", 2},
+	}
+
+	for i, elem := range testSuite {
+		trimmed, removed := elem.trim(elem.read)
+		if string(trimmed.Sequence) != elem.trimmed || removed != elem.removed {
+			t.Errorf("test %d: %s expected %s with %d removed, but got %s with %d removed", i, elem.name,
+				elem.trimmed, elem.removed, string(trimmed.Sequence), removed)
+		}
+		if !elem.read.hasQualities() {
+			continue
+		}
+		if len(trimmed.Encoded) != len(trimmed.Sequence) || len(trimmed.Decoded) != len(trimmed.Sequence) {
+			t.Errorf("test %d: %s expected the qualities to be cut with the sequence, but got %d and %d for %d bases", i, elem.name,
+				len(trimmed.Encoded), len(trimmed.Decoded), len(trimmed.Sequence))
+		}
+	}
+
+	// the qualities are those of the bases kept
+	trimmed, _ := qualityRead("NNACGN", 1, 2, 3, 4, 5, 6).TrimNs()
+	if string(trimmed.Encoded) != "$%&" || trimmed.Decoded[0] != 3 {
+		t.Errorf("expected the qualities $%%& (3 to 5), but got %q and %v", string(trimmed.Encoded), trimmed.Decoded)
+	}
+}
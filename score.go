@@ -0,0 +1,29 @@
+package gobioinfo
+
+// AlignmentScore is the result of a score-only alignment: the score of the best
+// alignment and where it ends. SubjectEnd and QueryEnd are the 0-based positions just
+// after the last aligned base of each sequence, so an alignment ending with the whole
+// query has a QueryEnd of len(query).
+type AlignmentScore struct {
+	Score      int
+	SubjectEnd int
+	QueryEnd   int
+}
+
+// AlignScore finds the score and end of the alignment that Align would return, without
+// the traceback or the gapped strings. It fills the matrices in the same way as Align, but
+// only keeps a few rows of them, so it is cheaper than Align when screening many reads,
+// such as for adapters.
+func (q NucleotideSequence) AlignScore(s NucleotideSequence, opts ...AlignOptions) AlignmentScore {
+	o := firstOptions(opts)
+	return q.alignScore(s, o.Mode, o.ends(), o.scoring(), o.Ties)
+}
+
+// alignScore fills the matrices with the kernel that align uses, one row at a time,
+// keeping only the edges of the matrices and the two rows being filled
+func (q NucleotideSequence) alignScore(s NucleotideSequence, mode AlignMode, ends FreeEnds, sc Scoring, ties TieBreak) AlignmentScore {
+	a := newAligner(q, s, mode, ends, sc, ties, nil)
+	a.edges()
+	end, score := a.findEnd(nil)
+	return AlignmentScore{Score: score, SubjectEnd: end.i, QueryEnd: end.j}
+}
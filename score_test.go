@@ -0,0 +1,288 @@
+package gobioinfo
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+)
+
+// func (q NucleotideSequence) AlignScore(s NucleotideSequence, opts ...AlignOptions) AlignmentScore
+func TestAlignScore(t *testing.T) {
+	fmt.Println("testing AlignScore()...")
+
+	type testPair struct {
+		query   string
+		subject string
+		opts    AlignOptions
+		score   AlignmentScore
+	}
+
+	testSuite := []testPair{
+		// an adapter at the end of a read
+		{"CACAGGGAGGACGATGTGTCAGTCAC", "GTGTCAGTCACTTCCAGCGG", AlignOptions{Ends: ThreePrimeEnds}, AlignmentScore{33, 11, 26}},
+		// with a mismatch
+		{"CACAGGGAGGACGATGTGTCAGACAC", "GTGTCAGTCACTTCCAGCGG", AlignOptions{Ends: ThreePrimeEnds}, AlignmentScore{26, 11, 26}},
+		// no alignment at all
+		{"AAAAAA", "CCCCCC", AlignOptions{}, AlignmentScore{0, 0, 6}},
+		{"AAAAAA", "CCCCCC", AlignOptions{Mode: Local}, AlignmentScore{0, 0, 0}},
+		// a deletion from the read, costing 6 to open and 3 to extend
+		{"ACGTACGTAATTACGTACGT", "ACGTACGTAACCTTACGTACGT", AlignOptions{Mode: Global}, AlignmentScore{51, 22, 20}},
+		{"GGGGACGTACGTGGGG", "TTACGTACGTTT", AlignOptions{Mode: Local}, AlignmentScore{24, 10, 12}},
+		// equally good ends are resolved the same way as by Align
+		{"ACGT", "ACGTACGT", AlignOptions{Scoring: UnitScoring}, AlignmentScore{4, 8, 4}},
+	}
+
+	for i, elem := range testSuite {
+		result := NucleotideSequence(elem.query).AlignScore(NucleotideSequence(elem.subject), elem.opts)
+		if result != elem.score {
+			t.Errorf("test %d: expected %+v, but got %+v", i, elem.score, result)
+		}
+	}
+
+	// the end is the same as that of the full alignment
+	r := rand.New(rand.NewSource(2))
+	endsList := []FreeEnds{AllEndsFree, ThreePrimeEnds, FivePrimeEnds, {QueryStart: true, SubjectEnd: true}, {SubjectStart: true, QueryEnd: true}}
+	for n := 0; n < 300; n++ {
+		subject := randomSequence(r, 10+r.Intn(50))
+		query := mutateSequence(r, subject[r.Intn(len(subject)/2):], 0.2)
+		query = append(randomSequence(r, r.Intn(10)), query...)
+		opts := AlignOptions{Mode: AlignMode(n % 3), Ends: endsList[n%len(endsList)], Scoring: []Scoring{DefaultScoring, UnitScoring, NUC44Scoring}[n%3]}
+
+		alignment := query.Align(subject, opts)
+		if alignment.ExpandedCIGAR == "" {
+			continue
+		}
+		subjectEnd := alignment.SubjectStart + len(alignment.ExpandedCIGAR) - strings.Count(alignment.ExpandedCIGAR, "j")
+		queryEnd := alignment.QueryStart + len(alignment.ExpandedCIGAR) - strings.Count(alignment.ExpandedCIGAR, "i")
+
+		result := query.AlignScore(subject, opts)
+		if result.SubjectEnd != subjectEnd || result.QueryEnd != queryEnd {
+			t.Errorf("%+v, query %s, subject %s: expected the alignment to end at s%d q%d, but got s%d q%d",
+				opts, string(query), string(subject), subjectEnd, queryEnd, result.SubjectEnd, result.QueryEnd)
+		}
+	}
+}
+
+// func (q NucleotideSequence) AlignScore(s NucleotideSequence, opts ...AlignOptions) AlignmentScore
+// func (q NucleotideSequence) Align(s NucleotideSequence, opts ...AlignOptions) PairWiseAlignment
+func TestAlignScoreMatchesAlign(t *testing.T) {
+	fmt.Println("testing that AlignScore() gives the score of Align()...")
+
+	r := rand.New(rand.NewSource(12))
+	endsList := []FreeEnds{AllEndsFree, ThreePrimeEnds, FivePrimeEnds, {QueryStart: true, SubjectEnd: true},
+		{SubjectStart: true, QueryEnd: true}, {QueryEnd: true, SubjectEnd: true}, {QueryStart: true, SubjectStart: true}}
+	scorings := []Scoring{DefaultScoring, UnitScoring, NUC44Scoring, LongReadScoring}
+
+	for _, mode := range []AlignMode{Global, Local, SemiGlobal} {
+		for _, ends := range endsList {
+			for _, sc := range scorings {
+				opts := AlignOptions{Mode: mode, Ends: ends, Scoring: sc}
+				for n := 0; n < 25; n++ {
+					subject := randomSequence(r, r.Intn(40))
+					query := randomSequence(r, r.Intn(40))
+					if n%2 == 0 && len(subject) > 0 {
+						query = mutateSequence(r, subject[r.Intn(len(subject)):], 0.2)
+						query = append(randomSequence(r, r.Intn(10)), query...)
+					}
+					if score, alignment := query.AlignScore(subject, opts), query.Align(subject, opts); score.Score != alignment.Score {
+						t.Errorf("%+v, query %s, subject %s: expected a score of %d, but got %d",
+							opts, string(query), string(subject), alignment.Score, score.Score)
+					}
+				}
+			}
+		}
+	}
+}
+
+// referenceAlignScore is alignScore as it was before the alignment inner loop was
+// rewritten around query base profiles, with the first end found kept on ties. The gap
+// matrices along penalized edges have since been fixed to start from negInf rather than
+// edgeGap, which is marked below.
+func referenceAlignScore(q NucleotideSequence, s NucleotideSequence, mode AlignMode, ends FreeEnds, sc Scoring) AlignmentScore {
+
+	lenI := len(s) + 1
+	lenJ := len(q) + 1
+
+	var (
+		h = sc.GapOpen   //gap opening penalty
+		g = sc.GapExtend //gap extension penalty
+	)
+
+	ends, edgeGap := modeEdges(mode, ends)
+	local := mode == Local
+
+	H := make([]int, lenI)
+	J := make([]int, lenI)
+
+	// the first row
+	I := edgeGap
+	J[0] = edgeGap
+	for i := 1; i < lenI; i++ {
+		J[i] = edgeGap
+		if ends.SubjectStart {
+			I = edgeGap
+			continue
+		}
+		I = maxInt([]int{H[i-1] - h, I - g})
+		H[i] = I
+		J[i] = negInf // fixed
+	}
+
+	best := AlignmentScore{}
+	if mode == SemiGlobal {
+		best.Score = negInf
+		if ends.QueryEnd {
+			best = AlignmentScore{Score: H[lenI-1], SubjectEnd: lenI - 1}
+		}
+	}
+
+	var profiles [128][]int
+	profile := func(queryBase rune) []int {
+		if queryBase >= 0 && queryBase < 128 && profiles[queryBase] != nil {
+			return profiles[queryBase]
+		}
+		scores := make([]int, lenI)
+		for i := 1; i < lenI; i++ {
+			scores[i], _ = sc.pair(s[i-1], queryBase)
+		}
+		if queryBase >= 0 && queryBase < 128 {
+			profiles[queryBase] = scores
+		}
+		return scores
+	}
+
+	for j := 1; j < lenJ; j++ {
+		scores := profile(q[j-1])
+
+		// the first column
+		diagonal := H[0]
+		if ends.QueryStart {
+			H[0], I, J[0] = 0, edgeGap, edgeGap
+		} else {
+			I = negInf // fixed
+			J[0] = maxInt([]int{H[0] - h, J[0] - g})
+			H[0] = J[0]
+		}
+
+		for i := 1; i < lenI; i++ {
+			if I -= g; H[i-1]-h > I {
+				I = H[i-1] - h
+			}
+			if J[i] -= g; H[i]-h > J[i] {
+				J[i] = H[i] - h
+			}
+
+			cell := diagonal + scores[i]
+			if I > cell {
+				cell = I
+			}
+			if J[i] > cell {
+				cell = J[i]
+			}
+			if local && cell <= 0 {
+				cell = 0
+			}
+
+			diagonal = H[i]
+			H[i] = cell
+
+			if local && cell > best.Score {
+				best = AlignmentScore{Score: cell, SubjectEnd: i, QueryEnd: j}
+			}
+		}
+
+		if mode == SemiGlobal && ends.QueryEnd && H[lenI-1] > best.Score {
+			best = AlignmentScore{Score: H[lenI-1], SubjectEnd: lenI - 1, QueryEnd: j}
+		}
+	}
+
+	if local {
+		return best
+	}
+
+	end := AlignmentScore{Score: H[lenI-1], SubjectEnd: lenI - 1, QueryEnd: lenJ - 1}
+	if mode == SemiGlobal && ends.SubjectEnd {
+		for i := 0; i < lenI; i++ {
+			if H[i] > end.Score {
+				end = AlignmentScore{Score: H[i], SubjectEnd: i, QueryEnd: lenJ - 1}
+			}
+		}
+	}
+	if mode == SemiGlobal && ends.QueryEnd && best.Score > end.Score {
+		end = best
+	}
+	return end
+}
+
+func TestAlignScoreReference(t *testing.T) {
+	fmt.Println("testing AlignScore() against the implementation before query base profiles...")
+
+	r := rand.New(rand.NewSource(14))
+	endsList := []FreeEnds{AllEndsFree, ThreePrimeEnds, FivePrimeEnds, {QueryStart: true, SubjectEnd: true}, {SubjectStart: true, QueryEnd: true}, {QueryEnd: true, SubjectEnd: true}}
+	scorings := []Scoring{DefaultScoring, UnitScoring, NUC44Scoring, {Match: 2, Mismatch: -3, GapOpen: 5, GapExtend: 2, N: -1}}
+
+	for n := 0; n < 2000; n++ {
+		subject := randomSequence(r, r.Intn(50))
+		query := randomSequence(r, r.Intn(50))
+		if n%2 == 0 && len(subject) > 0 {
+			query = mutateSequence(r, subject[r.Intn(len(subject)):], 0.2)
+			query = append(randomSequence(r, r.Intn(10)), query...)
+		}
+		opts := AlignOptions{Mode: AlignMode(r.Intn(3)), Ends: endsList[r.Intn(len(endsList))], Scoring: scorings[r.Intn(len(scorings))]}
+
+		expected := referenceAlignScore(query, subject, opts.Mode, opts.ends(), opts.scoring())
+		if result := query.AlignScore(subject, opts); result != expected {
+			t.Errorf("test %d: %+v, query %s, subject %s: expected %+v, but got %+v",
+				n, opts, string(query), string(subject), expected, result)
+		}
+	}
+}
+
+// benchmarkReads returns the reads of sample_50.fastq
+func benchmarkReads(b *testing.B) []FASTQRead {
+	file, err := os.Open("sample_50.fastq")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer file.Close()
+
+	var reads []FASTQRead
+	scanner := NewFASTQScanner(file)
+	for {
+		read, err := scanner.NextRead()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			b.Fatal(err)
+		}
+		reads = append(reads, read)
+	}
+	return reads
+}
+
+// the 3' adapter used in TestSGAlign
+var benchmarkAdapter = NewDNASequence("GTGTCAGTCACTTCCAGCGGTCGTATGCCGTCTTCTGCTTG")
+
+func BenchmarkAlignScore(b *testing.B) {
+	reads := benchmarkReads(b)
+	opts := AlignOptions{Ends: ThreePrimeEnds}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, read := range reads {
+			read.Sequence.AlignScore(benchmarkAdapter.Sequence, opts)
+		}
+	}
+}
+
+func BenchmarkSG3pAlign(b *testing.B) {
+	reads := benchmarkReads(b)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, read := range reads {
+			read.Sequence.SG3pAlign(benchmarkAdapter.Sequence)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package gobioinfo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Scoring holds the scores used by the alignment algorithms. Gap penalties are given as
+// positive numbers and subtracted: a gap of length k costs GapOpen + (k-1)*GapExtend.
+type Scoring struct {
+	Match     int // score for a pair of identical bases
+	Mismatch  int // score for a pair of different bases, usually negative
+	GapOpen   int // penalty for the first position of a gap
+	GapExtend int // penalty for each further position of a gap
+	// N is the score for pairing an N in either sequence with any other base, which is
+	// reported as neutral rather than as a match or mismatch; N against N stays a match,
+	// as the aligner has always scored it. Other ambiguity codes which may stand for the
+	// same base (eg. R against A, or R against S) are also neutral, but score a full
+	// Match; use a Matrix such as NUC44 to score them less than a match.
+	N int
+	// Matrix, if set, gives the score for every pair of bases and replaces Match,
+	// Mismatch and N
+	Matrix *SubstitutionMatrix
+}
+
+// pair scores the alignment of base a against base b and classifies it as a match,
+// mismatch or neutral position. Bases are compared case-insensitively with IUPAC
+// ambiguity codes expanded on both sides: identical unambiguous bases (and N against N)
+// are a match, bases whose codes have at least one possible base in common are neutral (scored N if either
+// is an N, and Match otherwise) and anything else is a mismatch. If a Matrix is set it
+// supplies the score, but the classification stays the same.
+func (sc *Scoring) pair(a rune, b rune) (score int, origin int) {
+	origin = classifyPair(a, b)
+	if sc.Matrix != nil {
+		return sc.Matrix.Score(a, b), origin
+	}
+	switch {
+	case origin == match:
+		return sc.Match, origin
+	case origin == mismatch:
+		return sc.Mismatch, origin
+	case iupacBases(a) == anyBase || iupacBases(b) == anyBase:
+		return sc.N, origin
+	}
+	return sc.Match, origin
+}
+
+// bit flags for the four nucleotides, combined to represent the IUPAC ambiguity codes
+const (
+	baseA = 1 << iota
+	baseC
+	baseG
+	baseT
+	anyBase = baseA | baseC | baseG | baseT
+)
+
+// iupacCodes maps each upper case IUPAC nucleotide code to the set of bases it stands for;
+// U is treated as T
+var iupacCodes = [128]uint8{
+	'A': baseA,
+	'C': baseC,
+	'G': baseG,
+	'T': baseT,
+	'U': baseT,
+	'R': baseA | baseG,
+	'Y': baseC | baseT,
+	'S': baseC | baseG,
+	'W': baseA | baseT,
+	'K': baseG | baseT,
+	'M': baseA | baseC,
+	'B': baseC | baseG | baseT,
+	'D': baseA | baseG | baseT,
+	'H': baseA | baseC | baseT,
+	'V': baseA | baseC | baseG,
+	'N': anyBase,
+}
+
+// iupacBases returns the set of bases a nucleotide code stands for, or 0 if it is not
+// an IUPAC code
+func iupacBases(r rune) uint8 {
+	r = unicode.ToUpper(r)
+	if r < 0 || int(r) >= len(iupacCodes) {
+		return 0
+	}
+	return iupacCodes[r]
+}
+
+// classifyPair decides whether aligning base a against base b is a match, a mismatch
+// or neutral, as described for Scoring.pair
+func classifyPair(a rune, b rune) int {
+	setA, setB := iupacBases(a), iupacBases(b)
+	switch {
+	case setA == 0 || setB == 0:
+		// not nucleotide codes, so only an exact match counts
+		if unicode.ToUpper(a) == unicode.ToUpper(b) {
+			return match
+		}
+		return mismatch
+	case setA == setB && setA&(setA-1) == 0:
+		// the same single base
+		return match
+	case setA == anyBase && setB == anyBase:
+		// N against N, which is a match as it was before ambiguity codes were handled
+		return match
+	case setA&setB != 0:
+		return neutral
+	}
+	return mismatch
+}
+
+// SubstitutionMatrix holds a score for aligning each pair of characters, such as the
+// NUC.4.4 matrix used by EMBOSS and BLAST. Lookups are case-insensitive.
+type SubstitutionMatrix struct {
+	scores [128][128]int
+	// Unknown is the score for any pair including a character not in the matrix
+	Unknown int
+}
+
+// Score returns the score for aligning a against b
+func (m *SubstitutionMatrix) Score(a rune, b rune) int {
+	a, b = unicode.ToUpper(a), unicode.ToUpper(b)
+	if a < 0 || b < 0 || int(a) >= len(m.scores) || int(b) >= len(m.scores) {
+		return m.Unknown
+	}
+	return m.scores[a][b]
+}
+
+// ParseSubstitutionMatrix reads a substitution matrix in the standard NCBI text format:
+// lines starting with "#" are comments, the first other line lists the column characters,
+// and each following line gives a row character and then its score against each column.
+// A "*" row and column, if present, sets the Unknown score; otherwise it is the lowest
+// score in the matrix.
+func ParseSubstitutionMatrix(r io.Reader) (*SubstitutionMatrix, error) {
+
+	scanner := bufio.NewScanner(r)
+
+	var columns []rune
+	var known [128]bool
+	m := &SubstitutionMatrix{}
+	lowest := 0
+	hasUnknown := false
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		// the header row of column characters
+		if columns == nil {
+			for _, field := range fields {
+				char, err := matrixChar(field)
+				if err != nil {
+					return nil, fmt.Errorf("substitution matrix line %d: %v", lineNumber, err)
+				}
+				columns = append(columns, char)
+			}
+			continue
+		}
+
+		row, err := matrixChar(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("substitution matrix line %d: %v", lineNumber, err)
+		}
+		if len(fields)-1 != len(columns) {
+			return nil, fmt.Errorf("substitution matrix line %d: expected %d scores, found %d",
+				lineNumber, len(columns), len(fields)-1)
+		}
+		for i, field := range fields[1:] {
+			score, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("substitution matrix line %d: %v", lineNumber, err)
+			}
+			if row == '*' || columns[i] == '*' {
+				if row == '*' && columns[i] == '*' {
+					continue
+				}
+				m.Unknown = score
+				hasUnknown = true
+				continue
+			}
+			m.scores[row][columns[i]] = score
+			known[row], known[columns[i]] = true, true
+			if score < lowest {
+				lowest = score
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if columns == nil {
+		return nil, fmt.Errorf("substitution matrix has no header row")
+	}
+
+	if !hasUnknown {
+		m.Unknown = lowest
+	}
+	// characters which are not in the matrix score as Unknown against everything
+	for a := range m.scores {
+		for b := range m.scores[a] {
+			if !known[a] || !known[b] {
+				m.scores[a][b] = m.Unknown
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// matrixChar parses a row or column label of a substitution matrix
+func matrixChar(field string) (rune, error) {
+	char := []rune(strings.ToUpper(field))
+	if len(char) != 1 || char[0] >= 128 {
+		return 0, fmt.Errorf("invalid matrix character %q", field)
+	}
+	return char[0], nil
+}
+
+// NewIUPACMatrix builds a SubstitutionMatrix over the IUPAC nucleotide codes from the
+// Match, Mismatch and N scores of a Scoring, following the same rules as a Scoring
+// without a Matrix
+func NewIUPACMatrix(sc Scoring) *SubstitutionMatrix {
+	sc.Matrix = nil
+	m := &SubstitutionMatrix{Unknown: sc.Mismatch}
+	for a := range m.scores {
+		for b := range m.scores[a] {
+			if iupacBases(rune(a)) == 0 || iupacBases(rune(b)) == 0 {
+				m.scores[a][b] = sc.Mismatch
+				continue
+			}
+			m.scores[a][b], _ = sc.pair(rune(a), rune(b))
+		}
+	}
+	return m
+}
+
+// nuc44 is the NUC.4.4 (EDNAFULL) matrix distributed with BLAST and EMBOSS
+const nuc44 = `#
+# This matrix was created by Todd Lowe   12/10/92
+#
+# Uses ambiguous nucleotide codes, probabilities rounded to
+#  nearest integer
+#
+# Lowest score = -4, Highest score = 5
+#
+    A   T   G   C   S   W   R   Y   K   M   B   V   H   D   N
+A   5  -4  -4  -4  -4   1   1  -4  -4   1  -4  -1  -1  -1  -2
+T  -4   5  -4  -4  -4   1  -4   1   1  -4  -1  -4  -1  -1  -2
+G  -4  -4   5  -4   1  -4   1  -4   1  -4  -1  -1  -4  -1  -2
+C  -4  -4  -4   5   1  -4  -4   1  -4   1  -1  -1  -1  -4  -2
+S  -4  -4   1   1  -1  -4  -2  -2  -2  -2  -1  -1  -3  -3  -1
+W   1   1  -4  -4  -4  -1  -2  -2  -2  -2  -3  -3  -1  -1  -1
+R   1  -4   1  -4  -2  -2  -1  -4  -2  -2  -3  -1  -3  -1  -1
+Y  -4   1  -4   1  -2  -2  -4  -1  -2  -2  -1  -3  -1  -3  -1
+K  -4   1   1  -4  -2  -2  -2  -2  -1  -4  -1  -3  -3  -1  -1
+M   1  -4  -4   1  -2  -2  -2  -2  -4  -1  -3  -1  -1  -3  -1
+B  -4  -1  -1  -1  -1  -3  -3  -1  -1  -3  -1  -2  -2  -2  -1
+V  -1  -4  -1  -1  -1  -3  -1  -3  -3  -1  -2  -1  -2  -2  -1
+H  -1  -1  -4  -1  -3  -1  -3  -1  -3  -1  -2  -2  -1  -2  -1
+D  -1  -1  -1  -4  -3  -1  -1  -3  -1  -3  -2  -2  -2  -1  -1
+N  -2  -2  -2  -2  -1  -1  -1  -1  -1  -1  -1  -1  -1  -1  -1
+`
+
+// NUC44 is the NUC.4.4 (EDNAFULL) DNA substitution matrix, with U scored as T
+var NUC44 = func() *SubstitutionMatrix {
+	m, err := ParseSubstitutionMatrix(strings.NewReader(nuc44))
+	if err != nil {
+		panic(err)
+	}
+	for b := range m.scores {
+		m.scores['U'][b] = m.scores['T'][b]
+		m.scores[b]['U'] = m.scores[b]['T']
+	}
+	return m
+}()
+
+// NUC44Scoring uses the NUC.4.4 matrix with a gap opening penalty of 10 and an extension
+// penalty of 1. EMBOSS needle and water default to 10 and 0.5, but penalties here are
+// whole numbers, so long gaps cost more than they do in EMBOSS and alignments with them
+// may differ.
+var NUC44Scoring = Scoring{Matrix: NUC44, GapOpen: 10, GapExtend: 1}
+
+// Scoring presets
+var (
+	// DefaultScoring is tuned for finding short adapters and linkers in Illumina reads,
+	// and is used whenever no Scoring is given
+	DefaultScoring = Scoring{Match: 3, Mismatch: -4, GapOpen: 6, GapExtend: 3, N: 0}
+
+	// LongReadScoring is more tolerant of the indels in nanopore and PacBio CLR reads,
+	// similar to minimap2's map-ont preset
+	LongReadScoring = Scoring{Match: 2, Mismatch: -4, GapOpen: 6, GapExtend: 2, N: 0}
+
+	// BLASTNScoring matches the blastn defaults (reward 2, penalty -3, gap costs 5/2)
+	BLASTNScoring = Scoring{Match: 2, Mismatch: -3, GapOpen: 7, GapExtend: 2, N: 0}
+
+	// UnitScoring scores matches +1 and mismatches and gap positions -1, so that the
+	// score of an alignment is its matches minus its edits
+	UnitScoring = Scoring{Match: 1, Mismatch: -1, GapOpen: 1, GapExtend: 1, N: 0}
+
+	// EditScoring scores every mismatch and gap position -1 and matches 0, so that the
+	// score of a global alignment is minus its edit distance
+	EditScoring = Scoring{Match: 0, Mismatch: -1, GapOpen: 1, GapExtend: 1, N: 0}
+)
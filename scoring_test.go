@@ -0,0 +1,135 @@
+package gobioinfo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// func (sc *Scoring) pair(a rune, b rune) (score int, origin int) {}
+func TestScoringPair(t *testing.T) {
+	fmt.Println("testing Scoring.pair()...")
+
+	type testPair struct {
+		a      rune
+		b      rune
+		score  int
+		origin int
+	}
+
+	sc := DefaultScoring
+
+	testSuite := []testPair{
+		{'A', 'A', 3, match},
+		{'a', 'A', 3, match},
+		{'T', 'U', 3, match},
+		{'A', 'C', -4, mismatch},
+		{'N', 'G', 0, neutral},
+		{'G', 'N', 0, neutral},
+		{'N', 'N', 3, match},
+		{'n', 'N', 3, match},
+		{'R', 'A', 3, neutral},
+		{'C', 'R', -4, mismatch},
+		{'S', 'K', 3, neutral},
+		{'S', 'W', -4, mismatch},
+		{'-', '-', 3, match},
+		{'X', 'A', -4, mismatch},
+	}
+
+	for _, elem := range testSuite {
+		score, origin := sc.pair(elem.a, elem.b)
+		if score != elem.score || origin != elem.origin {
+			t.Errorf("%q/%q: expected score %d origin %d, but got %d %d",
+				elem.a, elem.b, elem.score, elem.origin, score, origin)
+		}
+		// ambiguity handling is symmetric
+		reverseScore, reverseOrigin := sc.pair(elem.b, elem.a)
+		if reverseScore != score || reverseOrigin != origin {
+			t.Errorf("%q/%q does not score the same as %q/%q", elem.b, elem.a, elem.a, elem.b)
+		}
+	}
+
+	matrixScoring := Scoring{Matrix: NUC44}
+	if score, origin := matrixScoring.pair('R', 'A'); score != 1 || origin != neutral {
+		t.Error("expected NUC.4.4 to score R/A as 1 and neutral, but got ", score, origin)
+	}
+
+	// N against N scores a match under the default scoring, as it always has, and N
+	// against any other base scores N
+	read := NucleotideSequence("ACGTNNACGT")
+	if a := read.SG3pAlign(NucleotideSequence("ACGTNNACGT")); a.Score != 30 || a.ExpandedCIGAR != "mmmmmmmmmm" {
+		t.Errorf("expected NN against NN to score 30 as mmmmmmmmmm, but got %d as %s", a.Score, a.ExpandedCIGAR)
+	}
+	if a := read.SG3pAlign(NucleotideSequence("ACGTACACGT")); a.Score != 24 || a.ExpandedCIGAR != "mmmmnnmmmm" {
+		t.Errorf("expected NN against AC to score 24 as mmmmnnmmmm, but got %d as %s", a.Score, a.ExpandedCIGAR)
+	}
+}
+
+// func ParseSubstitutionMatrix(r io.Reader) (*SubstitutionMatrix, error) {}
+func TestParseSubstitutionMatrix(t *testing.T) {
+	fmt.Println("testing ParseSubstitutionMatrix()...")
+
+	type testPair struct {
+		a     rune
+		b     rune
+		score int
+	}
+
+	testSuite := []testPair{
+		{'A', 'A', 5},
+		{'A', 'T', -4},
+		{'a', 'w', 1},
+		{'N', 'A', -2},
+		{'N', 'N', -1},
+		{'V', 'T', -4},
+		{'U', 'A', -4},
+		{'U', 'T', 5},
+		{'X', 'A', -4},
+	}
+
+	for _, elem := range testSuite {
+		if score := NUC44.Score(elem.a, elem.b); score != elem.score {
+			t.Errorf("expected NUC.4.4 %q/%q to be %d, but got %d", elem.a, elem.b, elem.score, score)
+		}
+	}
+
+	m, err := ParseSubstitutionMatrix(strings.NewReader("# comment\n   A  C  *\nA  2 -1 -9\nC -1  2 -9\n* -9 -9  1\n"))
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if m.Score('c', 'C') != 2 || m.Score('A', 'C') != -1 || m.Score('A', 'G') != -9 || m.Unknown != -9 {
+		t.Error("parsed matrix does not hold the expected scores")
+	}
+
+	badMatrices := []string{
+		"",
+		"   A  C\nA  2 -1\nC -1\n",
+		"   A  C\nA  2 x\n",
+		"   AC C\nA  2 1\n",
+	}
+	for _, elem := range badMatrices {
+		if _, err := ParseSubstitutionMatrix(strings.NewReader(elem)); err == nil {
+			t.Errorf("expected an error parsing %q", elem)
+		}
+	}
+}
+
+// func NewIUPACMatrix(sc Scoring) *SubstitutionMatrix {}
+func TestIUPACMatrixAlignment(t *testing.T) {
+	fmt.Println("testing alignment of degenerate bases...")
+
+	read := NucleotideSequence("GCTAGGGAGGACGATGCGGTGGTGATGCTGCCACATACACTAAGAAGGTCCTGGACGCGTGTAGTCACTTCCAGCGG")
+	linker := NucleotideSequence("GTRTNAGTCACTTCCAGCGGTCG")
+
+	matrixScoring := Scoring{Matrix: NewIUPACMatrix(DefaultScoring), GapOpen: 6, GapExtend: 3}
+
+	for _, alignment := range []PairWiseAlignment{
+		read.SG3pAlign(linker),
+		read.SG3pAlign(linker, AlignOptions{Scoring: matrixScoring}),
+	} {
+		if alignment.ExpandedCIGAR != "mmnmimmmmmmmmmmmmmmm" || alignment.QueryStart != 58 {
+			t.Error("unexpected alignment of a degenerate linker: ",
+				alignment.ExpandedCIGAR, alignment.QueryStart)
+		}
+	}
+}
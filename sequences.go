@@ -30,15 +30,98 @@ func NewNucleotideSequence(s string) NucleotideSequence {
 	return seq
 }
 
+// complements holds the complement of each IUPAC base, in upper and lower case, with 0
+// for characters which are not bases. U is complemented to A; the complement of A is T,
+// or U in an RNA sequence.
+var complements = func() [128]rune {
+	var table [128]rune
+	bases, paired := "ACGTURYKMSWBDHVN", "TGCAAYRMKSWVHDBN"
+	for k := range bases {
+		table[bases[k]] = rune(paired[k])
+		table[bases[k]+'a'-'A'] = rune(paired[k] + 'a' - 'A')
+	}
+	table['-'], table['.'] = '-', '.'
+	return table
+}()
+
+// isRNA reports whether the sequence has a U and no T
+func (q NucleotideSequence) isRNA() bool {
+	u := false
+	for _, base := range q {
+		switch base {
+		case 'T', 't':
+			return false
+		case 'U', 'u':
+			u = true
+		}
+	}
+	return u
+}
+
+// complement returns the complement of a base, with U in place of T if rna is set.
+// Characters which are not IUPAC bases are left as they are.
+func complement(base rune, rna bool) rune {
+	if base < 0 || base >= 128 || complements[base] == 0 {
+		return base
+	}
+	c := complements[base]
+	if rna {
+		switch c {
+		case 'T':
+			c = 'U'
+		case 't':
+			c = 'u'
+		}
+	}
+	return c
+}
+
+// ComplementInPlace replaces each base of the sequence with its IUPAC complement,
+// keeping its case. A sequence with a U and no T is taken to be RNA, so that A is
+// complemented to U.
+func (q NucleotideSequence) ComplementInPlace() {
+	rna := q.isRNA()
+	for k, base := range q {
+		q[k] = complement(base, rna)
+	}
+}
+
+// ReverseComplementInPlace reverses the sequence and replaces each base with its
+// complement, as ComplementInPlace does
+func (q NucleotideSequence) ReverseComplementInPlace() {
+	q.ComplementInPlace()
+	for k, l := 0, len(q)-1; k < l; k, l = k+1, l-1 {
+		q[k], q[l] = q[l], q[k]
+	}
+}
+
+// Complement returns a copy of the sequence with each base complemented, as
+// ComplementInPlace does
+func (q NucleotideSequence) Complement() NucleotideSequence {
+	c := append(NucleotideSequence(nil), q...)
+	c.ComplementInPlace()
+	return c
+}
+
+// ReverseComplement returns the reverse complement of the sequence, leaving the sequence
+// itself as it is
+func (q NucleotideSequence) ReverseComplement() NucleotideSequence {
+	c := append(NucleotideSequence(nil), q...)
+	c.ReverseComplementInPlace()
+	return c
+}
+
 // DNASequence is a struct representing a dna sequence, it has a sequence attribute
 // and can have more attribites later, like species, source etc.
 type DNASequence struct {
 	Sequence NucleotideSequence
 }
 
-// FASTARead is
+// FASTARead is a single record from a FASTA file: the ID is the first word of the
+// header line (without the leading ">") and the Description is the rest of that line
 type FASTARead struct {
-	ID string
+	ID          string
+	Description string
 	DNASequence
 }
 
@@ -26,3 +26,51 @@ func TestNewDNASequence(t *testing.T) {
 		}
 	}
 }
+
+// func (q NucleotideSequence) ReverseComplement() NucleotideSequence
+func TestReverseComplement(t *testing.T) {
+	fmt.Println("testing Complement() and ReverseComplement()...")
+
+	type testPair struct {
+		input             string
+		complement        string
+		reverseComplement string
+	}
+
+	testSuite := []testPair{
+		{"ACGTN", "TGCAN", "NACGT"},
+		{"acgtACGT", "tgcaTGCA", "ACGTacgt"},
+		// IUPAC ambiguity codes, gaps and characters which are not bases
+		{"RYKMSWBDHV-.", "YRMKSWVHDB-.", ".-BDHVWSKMRY"},
+		{"AC*GT", "TG*CA", "AC*GT"},
+		// RNA
+		{"ACGU", "UGCA", "ACGU"},
+		{"aaccu", "uugga", "agguu"},
+		// a U in DNA is complemented to A, but A stays paired with T
+		{"ATU", "TAA", "AAT"},
+		{"", "", ""},
+	}
+
+	for _, elem := range testSuite {
+		seq := NucleotideSequence(elem.input)
+		if c := seq.Complement(); string(c) != elem.complement {
+			t.Errorf("Complement() of %q: expected %q, but got %q", elem.input, elem.complement, string(c))
+		}
+		if rc := seq.ReverseComplement(); string(rc) != elem.reverseComplement {
+			t.Errorf("ReverseComplement() of %q: expected %q, but got %q", elem.input, elem.reverseComplement, string(rc))
+		}
+		if string(seq) != elem.input {
+			t.Errorf("expected %q to be left as it was, but got %q", elem.input, string(seq))
+		}
+
+		seq.ReverseComplementInPlace()
+		if string(seq) != elem.reverseComplement {
+			t.Errorf("ReverseComplementInPlace() of %q: expected %q, but got %q", elem.input, elem.reverseComplement, string(seq))
+		}
+		seq = NucleotideSequence(elem.input)
+		seq.ComplementInPlace()
+		if string(seq) != elem.complement {
+			t.Errorf("ComplementInPlace() of %q: expected %q, but got %q", elem.input, elem.complement, string(seq))
+		}
+	}
+}
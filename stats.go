@@ -0,0 +1,187 @@
+package gobioinfo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// AlignmentStats summarizes a PairWiseAlignment
+type AlignmentStats struct {
+	Score int
+	// NormalizedScore is the Score divided by the Length, so that alignments of different
+	// lengths can be compared
+	NormalizedScore float64
+	Length          int // number of columns of the alignment
+	Matches         int
+	Mismatches      int
+	Neutral         int // positions paired with an N or a compatible ambiguity code
+	// GapOpens is the number of gaps, counting a gap in the query next to one in the
+	// subject as two, and GapExtensions the number of positions after the first of each
+	GapOpens      int
+	GapExtensions int
+	// Identity is the percentage of the columns of the alignment which are matches
+	Identity float64
+	// QueryCoverage and SubjectCoverage are the percentages of each sequence which are
+	// within the alignment
+	QueryCoverage   float64
+	SubjectCoverage float64
+	// QueryEnd and SubjectEnd are the 0-based positions just after the last aligned base
+	// of each sequence
+	QueryEnd   int
+	SubjectEnd int
+}
+
+// Stats works out the statistics of the alignment from its ExpandedCIGAR
+func (a PairWiseAlignment) Stats() AlignmentStats {
+
+	stats := AlignmentStats{
+		Score:      a.Score,
+		Length:     len(a.ExpandedCIGAR),
+		QueryEnd:   a.QueryStart + a.QueryAlignLen,
+		SubjectEnd: a.SubjectStart + a.SubjectAlignLen,
+	}
+
+	var previous rune
+	for _, column := range a.ExpandedCIGAR {
+		switch column {
+		case 'm':
+			stats.Matches++
+		case 'x':
+			stats.Mismatches++
+		case 'n':
+			stats.Neutral++
+		case 'i', 'j':
+			if column == previous {
+				stats.GapExtensions++
+			} else {
+				stats.GapOpens++
+			}
+		}
+		previous = column
+	}
+
+	stats.NormalizedScore = ratio(a.Score, stats.Length, 1)
+	stats.Identity = ratio(stats.Matches, stats.Length, 100)
+	stats.QueryCoverage = ratio(a.QueryAlignLen, len(a.Query), 100)
+	stats.SubjectCoverage = ratio(a.SubjectAlignLen, len(a.Subject), 100)
+
+	return stats
+}
+
+// ratio returns n/d times scale, or 0 if d is 0
+func ratio(n int, d int, scale float64) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d) * scale
+}
+
+// alignmentField is a named value of an alignment, as written to JSON and TSV
+type alignmentField struct {
+	name  string
+	value interface{}
+}
+
+// fields returns every field of the alignment and its statistics, in the order they are
+// written
+func (a PairWiseAlignment) fields() []alignmentField {
+	stats := a.Stats()
+	return []alignmentField{
+		{"subject", string(a.Subject)},
+		{"query", string(a.Query)},
+		{"subject_start", a.SubjectStart},
+		{"subject_end", stats.SubjectEnd},
+		{"query_start", a.QueryStart},
+		{"query_end", stats.QueryEnd},
+		{"strand", a.Strand.String()},
+		{"subject_align_len", a.SubjectAlignLen},
+		{"query_align_len", a.QueryAlignLen},
+		{"expanded_cigar", a.ExpandedCIGAR},
+		{"cigar", a.CIGAR()},
+		{"score", stats.Score},
+		{"normalized_score", stats.NormalizedScore},
+		{"length", stats.Length},
+		{"matches", stats.Matches},
+		{"mismatches", stats.Mismatches},
+		{"neutral", stats.Neutral},
+		{"gap_opens", stats.GapOpens},
+		{"gap_extensions", stats.GapExtensions},
+		{"identity", stats.Identity},
+		{"query_coverage", stats.QueryCoverage},
+		{"subject_coverage", stats.SubjectCoverage},
+		{"gapped_subject", a.GappedSubject},
+		{"gapped_query", a.GappedQuery},
+		{"alignment_representation", a.AlignmentRepresentation},
+	}
+}
+
+// MarshalJSON writes the alignment and its statistics as a JSON object, with the
+// sequences as strings
+func (a PairWiseAlignment) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for k, field := range a.fields() {
+		if k > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(field.name)
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// AlignmentTSVWriter writes alignments and their statistics as tab separated values, one
+// alignment to a line under a header line naming the columns as in the JSON
+type AlignmentTSVWriter struct {
+	*bufio.Writer
+	started bool // whether the header line has been written
+}
+
+// NewAlignmentTSVWriter takes an io.Writer and returns an AlignmentTSVWriter
+func NewAlignmentTSVWriter(w io.Writer) AlignmentTSVWriter {
+	return AlignmentTSVWriter{Writer: bufio.NewWriter(w)}
+}
+
+// Write writes an alignment as a line of the table, after the header line if this is the
+// first
+func (w *AlignmentTSVWriter) Write(a PairWiseAlignment) error {
+
+	fields := a.fields()
+	names := make([]string, len(fields))
+	values := make([]string, len(fields))
+	for k, field := range fields {
+		names[k] = field.name
+		switch value := field.value.(type) {
+		case float64:
+			values[k] = strconv.FormatFloat(value, 'f', -1, 64)
+		default:
+			values[k] = fmt.Sprint(value)
+		}
+	}
+
+	if !w.started {
+		if _, err := w.WriteString(strings.Join(names, "\t") + "\n"); err != nil {
+			return err
+		}
+		w.started = true
+	}
+	_, err := w.WriteString(strings.Join(values, "\t") + "\n")
+	return err
+}
+
+// Close flushes the AlignmentTSVWriter buffer; it does not close the underlying writer
+func (w *AlignmentTSVWriter) Close() error {
+	return w.Flush()
+}
@@ -0,0 +1,132 @@
+package gobioinfo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// func (a PairWiseAlignment) Stats() AlignmentStats
+func TestAlignmentStats(t *testing.T) {
+	fmt.Println("testing PairWiseAlignment.Stats()...")
+
+	// two single base gaps and an N
+	aligned := NucleotideSequence("GATTNCAGGTCA").Align(NucleotideSequence("CCGATGCAGTCATT"), AlignOptions{})
+
+	// a two base gap and a mismatch
+	applied, err := PairWiseAlignment{Subject: NucleotideSequence("AACCGGTTAC"), Query: NucleotideSequence("ACCTTG")}.ApplyCIGAR(1, "3M2D3M")
+	if err != nil {
+		t.Fatal("unexpected error from ApplyCIGAR: ", err)
+	}
+
+	testSuite := []struct {
+		alignment PairWiseAlignment
+		stats     AlignmentStats
+	}{
+		{aligned, AlignmentStats{Score: 15, NormalizedScore: 1.25, Length: 12, Matches: 9, Neutral: 1, GapOpens: 2,
+			Identity: 75, QueryCoverage: 100, SubjectCoverage: 100 * 10.0 / 14, QueryEnd: 12, SubjectEnd: 12}},
+		{applied, AlignmentStats{Length: 8, Matches: 5, Mismatches: 1, GapOpens: 1, GapExtensions: 1,
+			Identity: 62.5, QueryCoverage: 100, SubjectCoverage: 80, QueryEnd: 6, SubjectEnd: 9}},
+		// no alignment at all
+		{NucleotideSequence("AAAA").LocalAlign(NucleotideSequence("CCCC"), AlignOptions{}), AlignmentStats{}},
+	}
+
+	for i, elem := range testSuite {
+		if stats := elem.alignment.Stats(); stats != elem.stats {
+			t.Errorf("test %d: expected %+v, but got %+v", i, elem.stats, stats)
+		}
+	}
+}
+
+func TestAlignmentStatsScore(t *testing.T) {
+	fmt.Println("testing that PairWiseAlignment.Stats() counts add up to the Score...")
+
+	// global and local alignments have no free end gaps, so every column is scored
+	r := rand.New(rand.NewSource(17))
+	scorings := []Scoring{DefaultScoring, UnitScoring, {Match: 2, Mismatch: -3, GapOpen: 5, GapExtend: 2, N: -1}}
+
+	for n := 0; n < 1000; n++ {
+		subject := randomSequence(r, 5+r.Intn(40))
+		query := mutateSequence(r, subject, 0.3)
+		sc := scorings[n%len(scorings)]
+		opts := AlignOptions{Mode: []AlignMode{Global, Local}[r.Intn(2)], Scoring: sc}
+
+		a := query.Align(subject, opts)
+		stats := a.Stats()
+		score := sc.Match*stats.Matches + sc.Mismatch*stats.Mismatches + sc.N*stats.Neutral -
+			sc.GapOpen*stats.GapOpens - sc.GapExtend*stats.GapExtensions
+		if score != a.Score {
+			t.Errorf("test %d: %+v, %s to %s: expected the counts of %s to score %d, but got %+v scoring %d",
+				n, opts, string(query), string(subject), a.ExpandedCIGAR, a.Score, stats, score)
+		}
+	}
+}
+
+// func (a PairWiseAlignment) MarshalJSON() ([]byte, error)
+func TestAlignmentJSON(t *testing.T) {
+	fmt.Println("testing PairWiseAlignment.MarshalJSON()...")
+
+	alignment := NucleotideSequence("GATTNCAGGTCA").Align(NucleotideSequence("CCGATGCAGTCATT"), AlignOptions{})
+	encoded, err := json.Marshal(alignment)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("could not decode %s: %v", encoded, err)
+	}
+	expected := map[string]interface{}{
+		"query":          "GATTNCAGGTCA",
+		"subject_start":  2.0,
+		"cigar":          "2M1I4M1I4M",
+		"score":          15.0,
+		"strand":         "+",
+		"identity":       75.0,
+		"gapped_subject": "GA-TGCA-GTCA",
+	}
+	for name, value := range expected {
+		if decoded[name] != value {
+			t.Errorf("expected %s to be %v, but got %v", name, value, decoded[name])
+		}
+	}
+	if len(decoded) != len(alignment.fields()) {
+		t.Errorf("expected %d fields, but got %d", len(alignment.fields()), len(decoded))
+	}
+}
+
+// func (w *AlignmentTSVWriter) Write(a PairWiseAlignment) error
+func TestAlignmentTSVWriter(t *testing.T) {
+	fmt.Println("testing AlignmentTSVWriter.Write()...")
+
+	var buf bytes.Buffer
+	w := NewAlignmentTSVWriter(&buf)
+	for _, query := range []string{"GATTNCAGGTCA", "GATGCAG"} {
+		if err := w.Write(NucleotideSequence(query).Align(NucleotideSequence("CCGATGCAGTCATT"), AlignOptions{})); err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and 2 lines, but got %q", buf.String())
+	}
+	header := strings.Split(lines[0], "\t")
+	if header[0] != "subject" || header[len(header)-1] != "alignment_representation" {
+		t.Errorf("unexpected header %q", lines[0])
+	}
+	for _, line := range lines[1:] {
+		if columns := strings.Split(line, "\t"); len(columns) != len(header) {
+			t.Errorf("expected %d columns, but got %d in %q", len(header), len(columns), line)
+		}
+	}
+	if expected := "CCGATGCAGTCATT\tGATGCAG\t2\t9\t0\t7\t+\t7\t7\tmmmmmmm\t7M\t21\t3\t7\t7\t0\t0\t0\t0\t100\t100\t50\tGATGCAG\tGATGCAG\t|||||||"; lines[2] != expected {
+		t.Errorf("expected the line %q, but got %q", expected, lines[2])
+	}
+}
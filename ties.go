@@ -0,0 +1,334 @@
+package gobioinfo
+
+import (
+	"sort"
+)
+
+/*
+Choosing between equally scoring alignments.
+
+An adapter may match a read equally well in two places, or a mismatch may score the same
+as a pair of gaps. Align settles such ties by the TieBreak in AlignOptions, so the same
+pair of sequences always gives the same alignment. CoOptimalAlignments returns every
+alignment with the best score instead, and TopAlignments the best few alignments which do
+not overlap.
+
+Both follow the traceback through the full matrices, taking each step which gives the
+score of a cell rather than just the one kept in D, so they use more memory than Align on
+long sequences. As in align, the traceback stays in the gap matrix I or J for the length
+of a gap, so that every alignment found scores as much as the cell it ends at.
+*/
+
+// EndTie selects which of several equally scoring cells an alignment ends at
+type EndTie int
+
+// End tie-breaks
+const (
+	// FirstEnd keeps the end found first: the bottom right corner of the alignment
+	// matrices, then the first along the last row (from the start of the subject) and
+	// then the first along the last column (from the start of the query). In a local
+	// alignment it is the first cell in order of query and then subject position.
+	FirstEnd EndTie = iota
+	// LeftmostEnd takes the end nearest the start of the subject, and then the query
+	LeftmostEnd
+	// RightmostEnd takes the end furthest along the subject, and then the query
+	RightmostEnd
+)
+
+// TieBreak selects between equally scoring alignments. The zero value takes the first end
+// found, and during the traceback prefers a match or mismatch to a gap, and a subject
+// base against a gap ("i") to a query base against a gap ("j").
+type TieBreak struct {
+	Ends EndTie
+	// GapsFirst prefers either gap to a match or mismatch during the traceback
+	GapsFirst bool
+}
+
+// tracer follows every equally scoring traceback through the full matrices
+type tracer struct {
+	a       *aligner
+	lenI    int
+	H, I, J []int
+	seen    map[tracedAlignment]bool // the alignments found so far
+}
+
+// tracedAlignment identifies an alignment found by a tracer
+type tracedAlignment struct {
+	subjectStart, queryStart int
+	cigar                    string
+}
+
+// newTracer fills the matrices for aligning the query to the subject
+func newTracer(q NucleotideSequence, s NucleotideSequence, opts AlignOptions) *tracer {
+	a := newAligner(q, s, opts.Mode, opts.ends(), opts.scoring(), opts.Ties, nil)
+	t := &tracer{a: a, lenI: len(s) + 1, seen: make(map[tracedAlignment]bool)}
+	t.H, t.I, t.J, _ = a.matrices()
+	return t
+}
+
+// scoredEnd is a cell where an alignment may end, and its score
+type scoredEnd struct {
+	position matrixPosition
+	score    int
+}
+
+// ends returns the cells where an alignment may end, best first, with ties in the order
+// of the TieBreak
+func (t *tracer) ends() []scoredEnd {
+
+	a := t.a
+	lenI := t.lenI
+	lenJ := len(a.q) + 1
+
+	var ends []scoredEnd
+	add := func(i int, j int) {
+		ends = append(ends, scoredEnd{matrixPosition{i: i, j: j}, t.H[j*lenI+i]})
+	}
+
+	// the cells in the order findEnd meets them
+	switch {
+	case a.local:
+		for j := 1; j < lenJ; j++ {
+			for i := 1; i < lenI; i++ {
+				if t.H[j*lenI+i] > 0 {
+					add(i, j)
+				}
+			}
+		}
+		if ends == nil {
+			add(0, 0)
+		}
+	default:
+		add(lenI-1, lenJ-1)
+		if a.mode != SemiGlobal {
+			break
+		}
+		if a.ends.SubjectEnd {
+			for i := 0; i < lenI-1; i++ {
+				add(i, lenJ-1)
+			}
+		}
+		if a.ends.QueryEnd {
+			for j := 0; j < lenJ-1; j++ {
+				add(lenI-1, j)
+			}
+		}
+	}
+
+	sort.SliceStable(ends, func(x int, y int) bool {
+		return a.betterEnd(ends[x].score, ends[x].position, ends[y].score, ends[y].position)
+	})
+	return ends
+}
+
+// traceMove is a step of the traceback: the movement it adds to the CIGAR (0 for a step
+// from H into a gap matrix, which stays at the same cell), and the cell and matrix it
+// goes on to
+type traceMove struct {
+	move     uint8
+	position matrixPosition
+	state    traceState
+}
+
+// moves returns the steps which give the score of the cell at p in the matrix state, in
+// the order of the TieBreak, or none if an alignment starts there. Inside a gap, closing
+// it (opening it from H) comes before extending it, as in align.
+func (t *tracer) moves(p matrixPosition, state traceState) []traceMove {
+
+	a := t.a
+
+	// along the first row and column there is only one way back
+	if p.i == 0 || p.j == 0 {
+		d := a.colD[p.j]
+		if p.j == 0 {
+			d = a.rowD[p.i]
+		}
+		move, next, nextState, start := a.traceStep(p, state, d)
+		if start {
+			return nil
+		}
+		return []traceMove{{move, next, nextState}}
+	}
+
+	k := p.j*t.lenI + p.i
+	left := matrixPosition{i: p.i - 1, j: p.j}
+	up := matrixPosition{i: p.i, j: p.j - 1}
+	var moves []traceMove
+
+	switch state {
+	case inI:
+		if t.H[k-1]-a.h == t.I[k] {
+			moves = append(moves, traceMove{insI, left, inH})
+		}
+		if t.I[k-1]-a.g == t.I[k] {
+			moves = append(moves, traceMove{insI, left, inI})
+		}
+		return moves
+	case inJ:
+		if t.H[k-t.lenI]-a.h == t.J[k] {
+			moves = append(moves, traceMove{insJ, up, inH})
+		}
+		if t.J[k-t.lenI]-a.g == t.J[k] {
+			moves = append(moves, traceMove{insJ, up, inJ})
+		}
+		return moves
+	}
+
+	h := t.H[k]
+	if a.local && h <= 0 {
+		return nil
+	}
+
+	profile := a.profile(a.q[p.j-1])
+	var diagonal, gaps []traceMove
+	if t.H[k-t.lenI-1]+profile.scores[p.i] == h {
+		diagonal = append(diagonal, traceMove{profile.origins[p.i], matrixPosition{i: p.i - 1, j: p.j - 1}, inH})
+	}
+	if t.I[k] == h {
+		gaps = append(gaps, traceMove{0, p, inI})
+	}
+	if t.J[k] == h {
+		gaps = append(gaps, traceMove{0, p, inJ})
+	}
+
+	if a.ties.GapsFirst {
+		return append(gaps, diagonal...)
+	}
+	return append(diagonal, gaps...)
+}
+
+// traceFrame is a cell still to be visited by trace: the step that reaches it, how many
+// movements of revCIGAR come before that step, and the last cell added to revCIGAR
+type traceFrame struct {
+	traceMove
+	depth int
+	last  matrixPosition
+}
+
+// trace follows each traceback from the cell p of the matrix state, adding the alignments
+// it finds to alignments until there are limit of them (if limit is more than 0).
+// revCIGAR holds the movements from the end to p, and last the last cell added to it. If
+// all is false only the first step from each cell is followed, as Align does. The
+// tracebacks are followed depth first from a stack rather than by recursion, as they are
+// as long as the alignments.
+func (t *tracer) trace(p matrixPosition, state traceState, last matrixPosition, revCIGAR []int, end scoredEnd, all bool, limit int, alignments *[]PairWiseAlignment) {
+
+	stack := []traceFrame{{traceMove{0, p, state}, len(revCIGAR), last}}
+	for len(stack) > 0 && (limit <= 0 || len(*alignments) < limit) {
+		frame := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		// the movements up to this cell are the same as for the cell the step came from,
+		// which its other steps, visited before, have left in place
+		revCIGAR = revCIGAR[:frame.depth]
+		if frame.move != 0 {
+			revCIGAR = append(revCIGAR, int(frame.move))
+		}
+		p, last = frame.position, frame.last
+
+		moves := t.moves(p, frame.state)
+		if len(moves) == 0 {
+			alignment := t.a.alignment(revCIGAR, last, end.score)
+
+			// where a gap can be closed and reopened for the cost of extending it, two
+			// tracebacks give the same alignment
+			key := tracedAlignment{alignment.SubjectStart, alignment.QueryStart, alignment.ExpandedCIGAR}
+			if !t.seen[key] {
+				t.seen[key] = true
+				*alignments = append(*alignments, alignment)
+			}
+			continue
+		}
+		if !all {
+			moves = moves[:1]
+		}
+
+		// pushed last first, so that the steps are followed in the order of the TieBreak
+		for k := len(moves) - 1; k >= 0; k-- {
+			next := traceFrame{moves[k], len(revCIGAR), last}
+			if moves[k].move != 0 {
+				next.last = p
+			}
+			stack = append(stack, next)
+		}
+	}
+}
+
+// CoOptimalAlignments returns the alignments of the query to the subject which score as
+// well as the one Align returns, up to limit of them (or all of them, if limit is 0). The
+// alignment Align returns is first, followed by the others in the order of the TieBreak.
+// There can be very many co-optimal alignments of long or repetitive sequences, so a
+// limit should usually be given.
+func (q NucleotideSequence) CoOptimalAlignments(s NucleotideSequence, limit int, opts ...AlignOptions) []PairWiseAlignment {
+
+	t := newTracer(q, s, firstOptions(opts))
+	ends := t.ends()
+
+	var alignments []PairWiseAlignment
+	for _, end := range ends {
+		if end.score != ends[0].score || (limit > 0 && len(alignments) >= limit) {
+			break
+		}
+		t.trace(end.position, inH, end.position, nil, end, true, limit, &alignments)
+	}
+
+	return alignments
+}
+
+// TopAlignments returns up to k of the best scoring alignments of the query to the subject,
+// best first, where each alignment ends in a different cell and does not pair any bases
+// which a better alignment has already paired. The first is the alignment Align returns,
+// and the rest are found in the same way from the next best ends, such as the other places
+// an adapter occurs in a read.
+func (q NucleotideSequence) TopAlignments(s NucleotideSequence, k int, opts ...AlignOptions) []PairWiseAlignment {
+
+	t := newTracer(q, s, firstOptions(opts))
+
+	var top []PairWiseAlignment
+	paired := make(map[matrixPosition]bool)
+
+	for _, end := range t.ends() {
+		if len(top) >= k {
+			break
+		}
+
+		var alignments []PairWiseAlignment
+		t.trace(end.position, inH, end.position, nil, end, false, 1, &alignments)
+		alignment := alignments[0]
+
+		// the cells of the bases the alignment pairs
+		var cells []matrixPosition
+		p := matrixPosition{i: alignment.SubjectStart, j: alignment.QueryStart}
+		for _, c := range alignment.ExpandedCIGAR {
+			switch c {
+			case 'i':
+				p.i++
+			case 'j':
+				p.j++
+			default:
+				p.i++
+				p.j++
+				cells = append(cells, p)
+			}
+		}
+
+		// after the first, alignments which pair no bases are of no interest
+		if len(cells) == 0 && len(top) > 0 {
+			continue
+		}
+
+		overlaps := false
+		for _, cell := range cells {
+			overlaps = overlaps || paired[cell]
+		}
+		if overlaps {
+			continue
+		}
+		for _, cell := range cells {
+			paired[cell] = true
+		}
+		top = append(top, alignment)
+	}
+
+	return top
+}
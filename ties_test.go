@@ -0,0 +1,174 @@
+package gobioinfo
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// func (q NucleotideSequence) Align(s NucleotideSequence, opts ...AlignOptions) PairWiseAlignment
+func TestTieBreak(t *testing.T) {
+	fmt.Println("testing Align() with a TieBreak...")
+
+	// a mismatch scores the same as a pair of gaps
+	gapOrMismatch := Scoring{Match: 1, Mismatch: -2, GapOpen: 1, GapExtend: 1}
+
+	type testPair struct {
+		query        string
+		subject      string
+		opts         AlignOptions
+		cigar        string
+		subjectStart int
+		queryStart   int
+	}
+
+	testSuite := []testPair{
+		// two copies of the query in the subject: the corner is found first
+		{"ACGT", "ACGTTTTTACGT", AlignOptions{}, "mmmm", 8, 0},
+		{"ACGT", "ACGTTTTTACGT", AlignOptions{Ties: TieBreak{Ends: LeftmostEnd}}, "mmmm", 0, 0},
+		{"ACGT", "ACGTTTTTACGT", AlignOptions{Ties: TieBreak{Ends: RightmostEnd}}, "mmmm", 8, 0},
+		// and in a local alignment the first copy along the subject
+		{"ACGT", "ACGTTTTTACGT", AlignOptions{Mode: Local}, "mmmm", 0, 0},
+		{"ACGT", "ACGTTTTTACGT", AlignOptions{Mode: Local, Ties: TieBreak{Ends: RightmostEnd}}, "mmmm", 8, 0},
+		// a mismatch or two gaps
+		{"ACA", "AGA", AlignOptions{Mode: Global, Scoring: gapOrMismatch}, "mxm", 0, 0},
+		{"ACA", "AGA", AlignOptions{Mode: Global, Scoring: gapOrMismatch, Ties: TieBreak{GapsFirst: true}}, "mjim", 0, 0},
+	}
+
+	for i, elem := range testSuite {
+		result := NucleotideSequence(elem.query).Align(NucleotideSequence(elem.subject), elem.opts)
+		if result.ExpandedCIGAR != elem.cigar || result.SubjectStart != elem.subjectStart || result.QueryStart != elem.queryStart {
+			t.Errorf("test %d: expected %s at s%d q%d, but got %s at s%d q%d", i, elem.cigar, elem.subjectStart, elem.queryStart,
+				result.ExpandedCIGAR, result.SubjectStart, result.QueryStart)
+		}
+	}
+}
+
+// func (q NucleotideSequence) CoOptimalAlignments(s NucleotideSequence, limit int, opts ...AlignOptions) []PairWiseAlignment
+func TestCoOptimalAlignments(t *testing.T) {
+	fmt.Println("testing CoOptimalAlignments()...")
+
+	opts := AlignOptions{Mode: Global, Scoring: Scoring{Match: 1, Mismatch: -2, GapOpen: 1, GapExtend: 1}}
+	alignments := NucleotideSequence("ACA").CoOptimalAlignments(NucleotideSequence("AGA"), 0, opts)
+
+	var cigars []string
+	for _, alignment := range alignments {
+		cigars = append(cigars, alignment.ExpandedCIGAR)
+		if alignment.Score != 0 {
+			t.Errorf("expected %s to score 0, but got %d", alignment.ExpandedCIGAR, alignment.Score)
+		}
+	}
+	if expected := []string{"mxm", "mjim", "mijm"}; !reflect.DeepEqual(cigars, expected) {
+		t.Errorf("expected %v, but got %v", expected, cigars)
+	}
+
+	if limited := NucleotideSequence("ACA").CoOptimalAlignments(NucleotideSequence("AGA"), 2, opts); len(limited) != 2 {
+		t.Errorf("expected 2 alignments, but got %d", len(limited))
+	}
+}
+
+// func (q NucleotideSequence) TopAlignments(s NucleotideSequence, k int, opts ...AlignOptions) []PairWiseAlignment
+func TestTopAlignments(t *testing.T) {
+	fmt.Println("testing TopAlignments()...")
+
+	query := NucleotideSequence("GATTACA")
+	subject := NucleotideSequence("CCGATTACACCCCGATGACACC")
+
+	top := query.TopAlignments(subject, 2, AlignOptions{Mode: Local})
+	if len(top) != 2 {
+		t.Fatalf("expected 2 alignments, but got %+v", top)
+	}
+	if top[0].SubjectStart != 2 || top[0].ExpandedCIGAR != "mmmmmmm" || top[0].Score != 21 {
+		t.Errorf("expected the exact copy first, but got %s at %d scoring %d", top[0].ExpandedCIGAR, top[0].SubjectStart, top[0].Score)
+	}
+	if top[1].SubjectStart != 13 || top[1].ExpandedCIGAR != "mmmxmmm" || top[1].Score != 14 {
+		t.Errorf("expected the copy with a mismatch second, but got %s at %d scoring %d", top[1].ExpandedCIGAR, top[1].SubjectStart, top[1].Score)
+	}
+}
+
+func TestTiesAgreeWithAlign(t *testing.T) {
+	fmt.Println("testing that tie-breaks agree between Align(), AlignScore() and the enumerations...")
+
+	r := rand.New(rand.NewSource(16))
+	ties := []TieBreak{{}, {Ends: LeftmostEnd}, {Ends: RightmostEnd, GapsFirst: true}}
+
+	for n := 0; n < 500; n++ {
+		query := randomSequence(r, 1+r.Intn(15))
+		subject := mutateSequence(r, append(randomSequence(r, r.Intn(8)), query...), 0.3)
+		opts := AlignOptions{
+			Mode:    AlignMode(r.Intn(3)),
+			Ends:    []FreeEnds{AllEndsFree, ThreePrimeEnds, FivePrimeEnds}[r.Intn(3)],
+			Scoring: UnitScoring,
+			Ties:    ties[r.Intn(len(ties))],
+		}
+
+		expected := query.Align(subject, opts)
+		score := query.AlignScore(subject, opts)
+		coOptimal := query.CoOptimalAlignments(subject, 20, opts)
+		top := query.TopAlignments(subject, 3, opts)
+
+		end := AlignmentScore{
+			Score:      expected.Score,
+			SubjectEnd: expected.SubjectStart + expected.SubjectAlignLen,
+			QueryEnd:   expected.QueryStart + expected.QueryAlignLen,
+		}
+		if expected.ExpandedCIGAR != "" && score != end {
+			t.Errorf("%+v, %s to %s: AlignScore gave %+v, but Align ends at %+v", opts, string(query), string(subject), score, end)
+		}
+		if !reflect.DeepEqual(coOptimal[0], expected) || !reflect.DeepEqual(top[0], expected) {
+			t.Errorf("%+v, %s to %s: expected %+v first, but got %+v and %+v", opts, string(query), string(subject), expected, coOptimal[0], top[0])
+		}
+		for _, alignment := range coOptimal {
+			if alignment.Score != expected.Score {
+				t.Errorf("%+v, %s to %s: co-optimal alignment %+v scores differently from %+v", opts, string(query), string(subject), alignment, expected)
+			}
+		}
+	}
+}
+
+func TestEnumeratedAlignmentsScore(t *testing.T) {
+	fmt.Println("testing that CoOptimalAlignments() and TopAlignments() return alignments scoring their Score...")
+
+	r := rand.New(rand.NewSource(17))
+	scorings := []Scoring{DefaultScoring, UnitScoring, NUC44Scoring, {Match: 2, Mismatch: -3, GapOpen: 5, GapExtend: 2}}
+	ties := []TieBreak{{}, {Ends: LeftmostEnd}, {Ends: RightmostEnd, GapsFirst: true}}
+
+	failures := 0
+	for n := 0; n < 500; n++ {
+		subject := randomSequence(r, 5+r.Intn(25))
+		query := mutateSequence(r, subject, 0.3)
+		if n%2 == 0 {
+			query = randomSequence(r, 5+r.Intn(25))
+		}
+		opts := AlignOptions{
+			Mode:    AlignMode(r.Intn(3)),
+			Ends:    []FreeEnds{AllEndsFree, ThreePrimeEnds, FivePrimeEnds}[r.Intn(3)],
+			Scoring: scorings[r.Intn(len(scorings))],
+			Ties:    ties[r.Intn(len(ties))],
+		}
+
+		coOptimal := query.CoOptimalAlignments(subject, 50, opts)
+		top := query.TopAlignments(subject, 3, opts)
+
+		seen := make(map[string]bool)
+		for k, a := range append(coOptimal, top...) {
+			if k < len(coOptimal) {
+				key := fmt.Sprint(a.SubjectStart, a.QueryStart, a.ExpandedCIGAR)
+				if seen[key] && failures < 10 {
+					failures++
+					t.Errorf("test %d: %+v, %s to %s: %s at %d returned twice", n, opts, string(query), string(subject), a.ExpandedCIGAR, a.SubjectStart)
+				}
+				seen[key] = true
+			}
+			if !reachable(a.Score) {
+				continue
+			}
+			if score := cigarScore(a, opts.Mode, opts.ends(), opts.scoring()); score != a.Score && failures < 10 {
+				failures++
+				t.Errorf("test %d (%d): %+v, %s to %s: expected %s to score %d, but it scores %d",
+					n, k, opts, string(query), string(subject), a.ExpandedCIGAR, a.Score, score)
+			}
+		}
+	}
+}